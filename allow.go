@@ -0,0 +1,115 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// allowSet is the answer to a 405 or OPTIONS request: the methods with a
+// route for the path, plus HEAD for GET routes and OPTIONS. Sets are shared
+// between requests and must not be modified; header, the Allow header value,
+// is set on responses as is, like the Vary and Sunset headers.
+type allowSet struct {
+	methods []string
+	header  []string
+}
+
+func newAllowSet(methods []string) *allowSet {
+	if len(methods) == 0 {
+		return nil
+	}
+
+	methods = append([]string(nil), methods...)
+
+	// HEAD is answered by the GET route unless registered explicitly
+	hasGet, hasHead := false, false
+	for _, method := range methods {
+		hasGet = hasGet || method == http.MethodGet
+		hasHead = hasHead || method == http.MethodHead
+	}
+	if hasGet && !hasHead {
+		methods = append(methods, http.MethodHead)
+	}
+
+	methods = append(methods, http.MethodOptions)
+	sort.Strings(methods)
+
+	return &allowSet{
+		methods: methods,
+		header:  []string{strings.Join(methods, ", ")},
+	}
+}
+
+// allowSets holds the set of every combination of standard methods but
+// OPTIONS, indexed by the mask of their methodRoots indexes. They are built
+// once, so that neither registering routes nor answering 405 and OPTIONS
+// builds any.
+var allowSets = func() (sets [1 << methodRootSlots]*allowSet) {
+	options := 1 << methodRootIndex(http.MethodOptions)
+	for mask := 1; mask < len(sets); mask++ {
+		if mask&options != 0 {
+			continue
+		}
+		var methods []string
+		for i, method := range standardMethods {
+			if mask&(1<<i) != 0 {
+				methods = append(methods, method)
+			}
+		}
+		sets[mask] = newAllowSet(methods)
+	}
+	return sets
+}()
+
+// allowed returns the methods other than reqMethod with a route matching
+// path, or nil if there are none. Routers with standard methods only answer
+// with a prebuilt set, allocating nothing.
+func (rt *router) allowed(path, reqMethod string) *allowSet {
+	if path == "*" { // server-wide
+		return rt.globalAllowed
+	}
+
+	var (
+		mask  uint16
+		roots int
+	)
+	for i, root := range rt.methodRoots {
+		if root == nil {
+			continue
+		}
+		roots++
+		// Skip the requested method - we already tried this one
+		if method := standardMethods[i]; method == reqMethod || method == http.MethodOptions {
+			continue
+		}
+		if n, _ := root.match(path, nil, nil); n != nil {
+			mask |= 1 << i
+		}
+	}
+
+	// Custom methods have no bit in the mask
+	if len(rt.trees) > roots {
+		return rt.allowedSlow(path, reqMethod)
+	}
+	return allowSets[mask]
+}
+
+// allowedSlow computes the allowed methods for path by walking every tree.
+// An empty reqMethod with the path "*" lists the methods of all routes.
+func (rt *router) allowedSlow(path, reqMethod string) *allowSet {
+	var methods []string
+	for method, root := range rt.trees {
+		// Skip the requested method - we already tried this one
+		if method == reqMethod || method == http.MethodOptions {
+			continue
+		}
+		if path != "*" {
+			if n, _ := root.match(path, nil, nil); n == nil {
+				continue
+			}
+		}
+		methods = append(methods, method)
+	}
+	return newAllowSet(methods)
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowSetsPrecomputed(t *testing.T) {
+	app := New()
+	ok := func(c *Ctx) (any, error) { return nil, nil }
+	app.Get("/users/:id", ok)
+	app.Put("/users/:id", ok)
+	app.Delete("/users/:name", ok)
+	app.Post("/users/new", ok)
+	app.Get("/reports/:year/:month?", ok)
+	app.Patch("/reports/:year", ok)
+
+	rt := &app.table.Load().router
+	tests := []struct {
+		path   string
+		method string
+		allow  string
+	}{
+		{path: "/users/42", method: http.MethodPost, allow: "DELETE, GET, HEAD, OPTIONS, PUT"},
+		{path: "/users/new", method: http.MethodPatch, allow: "DELETE, GET, HEAD, OPTIONS, POST, PUT"},
+		{path: "/reports/2024", method: http.MethodPost, allow: "GET, HEAD, OPTIONS, PATCH"},
+		{path: "/reports/2024/05", method: http.MethodPost, allow: "GET, HEAD, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		allow := rt.allowed(tt.path, tt.method)
+		if allow == nil || allow.header[0] != tt.allow {
+			t.Fatalf("%s: expected Allow %q, got %+v", tt.path, tt.allow, allow)
+		}
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != tt.allow {
+			t.Fatalf("%s: expected 405 with Allow %q, got %d %q", tt.path, tt.allow, rec.Code, rec.Header().Get("Allow"))
+		}
+	}
+
+	if allows := testing.AllocsPerRun(100, func() {
+		rt.allowed("/users/42", http.MethodPost)
+		rt.allowed("/users/42", http.MethodOptions)
+		rt.allowed("/users/new", http.MethodPatch)
+	}); allows != 0 {
+		t.Fatalf("expected precomputed Allow sets, got %v allocs", allows)
+	}
+
+	// Changes are answered right away
+	app.Remove(http.MethodPut, "/users/:id")
+	app.Handle("PROPFIND", "/users/:id", ok)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users/42", nil))
+	if got := rec.Header().Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS, PROPFIND" {
+		t.Fatalf("expected Allow to follow route changes, got %q", got)
+	}
+}
+
+func TestAllowSetsForHosts(t *testing.T) {
+	app := New()
+	ok := func(c *Ctx) (any, error) { return nil, nil }
+	app.Get("/users/:id", ok)
+	app.Put("/users/:id", ok)
+	app.Host("api.example.com").Post("/items", ok)
+
+	serve := func(method, host, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Host = host
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Answers leave the route table alone
+	table := app.table.Load()
+	if rec := serve(http.MethodPost, "example.com", "/users/42"); rec.Header().Get("Allow") != "GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("expected Allow GET, HEAD, OPTIONS, PUT, got %q", rec.Header().Get("Allow"))
+	}
+	if app.table.Load() != table {
+		t.Fatalf("expected a 405 answer not to replace the route table")
+	}
+	for _, rt := range []*router{&table.router, &table.host("api.example.com").router} {
+		for _, path := range []string{"/users/42", "/items"} {
+			rt := rt
+			if allows := testing.AllocsPerRun(100, func() {
+				rt.allowed(path, http.MethodDelete)
+			}); allows != 0 {
+				t.Fatalf("%s: expected precomputed Allow sets, got %v allocs", path, allows)
+			}
+		}
+	}
+
+	if rec := serve(http.MethodGet, "api.example.com", "/items"); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "OPTIONS, POST" {
+		t.Fatalf("expected 405 with Allow OPTIONS, POST, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -3,16 +3,42 @@ package web
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/tabwriter"
 )
 
 const methodRootSlots = 9
 
+// standardMethods lists the methods with a fixed slot, in slot order.
+var standardMethods = [methodRootSlots]string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// methodAny is the method under which Any routes are registered and listed.
+const methodAny = "*"
+
+// mountParam is the catch-all param holding the path below a mount prefix.
+const mountParam = "mountpath"
+
 func methodRootIndex(method string) int {
 	switch method {
 	case http.MethodGet:
@@ -38,40 +64,79 @@ func methodRootIndex(method string) int {
 	}
 }
 
+// router holds the method trees of one routing scope: the application itself
+// or a host registered with Application.Host.
+type router struct {
+	trees            map[string]*node
+	methodRoots      [methodRootSlots]*node
+	anyRoot          *node
+	notFound         []*fallback
+	methodNotAllowed []*fallback
+	globalAllowed    *allowSet
+	host             string
+	hostParams       uint16
+	version          string
+}
+
 // Application is type of a web.Application
 type Application struct {
-	srv           *http.Server
-	trees         map[string]*node
-	methodRoots   [methodRootSlots]*node
-	info          *log.Logger
-	err           *log.Logger
-	cors          Cors
-	panic         Panic
-	errorHandler  ErrorHandler
-	middleware    Chain
-	readers       [mediaTypeSlots]Reader
-	writers       [mediaTypeSlots]Writer
-	hasReaders    bool
-	hasWriters    bool
-	paramsPool    sync.Pool
-	maxParams     uint16
-	globalAllowed []string
-
+	mu           sync.Mutex
+	table        atomic.Pointer[routeTable]
+	srv          *http.Server
+	named        map[string]*Route
+	info         *log.Logger
+	err          *log.Logger
+	cors         Cors
+	panic        Panic
+	errorHandler ErrorHandler
+	middleware   Chain
+	options      []RouteOption
+	pre          Chain
+	preNext      Next
+	readers      [mediaTypeSlots]Reader
+	writers      [mediaTypeSlots]Writer
+	hasReaders   bool
+	hasWriters   bool
+	mediaReaders map[string]Reader
+	mediaWriters []*mediaWriter
+	accepts      *acceptCache // of negotiations including mediaWriters
+	params       paramsPool
+
+	// NotFound and MethodNotAllowed answer unmatched requests when set.
+	// Otherwise ErrNotFound and ErrMethodNotAllowed go through the error
+	// handler like route errors.
 	NotFound         http.Handler
 	MethodNotAllowed http.Handler
+
+	// RedirectTrailingSlash redirects a request whose path did not match to
+	// the same path with (or without) a trailing slash when that route exists.
+	// GET requests are answered with 301, all other methods with 308.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath redirects a request whose path did not match to its
+	// cleaned form, with superfluous path elements like ../ or // removed and
+	// letter case corrected, when that route exists. Trailing slashes are
+	// fixed too if RedirectTrailingSlash is set.
+	RedirectFixedPath bool
+
+	// DefaultVersion is the API version of requests that name none, see
+	// Version.
+	DefaultVersion string
+
+	// VersionPrefix lets the path name the API version when set, e.g. "/v"
+	// routes "/v2/users" as "/users" of version 2.
+	VersionPrefix string
+
+	// AutoValidate makes TryParseBody and Bind check the structs they fill
+	// with Ctx.Validate. It is off by default, as tags written for another
+	// validator would fail every request.
+	AutoValidate bool
 }
 
 // New return *web.Application
 func New() *Application {
 	app := &Application{}
-	app.paramsPool.New = func() any {
-		n := app.maxParams
-		if n == 0 {
-			n = 1
-		}
-		ps := make(Params, 0, n)
-		return &ps
-	}
+	app.table.Store(&routeTable{})
 	return app
 }
 
@@ -100,29 +165,69 @@ func (app *Application) SetErrorHandler(handler ErrorHandler) {
 	app.errorHandler = handler
 }
 
-// RegisterReader registers a request body reader for a supported content type.
+// RegisterReader registers a request body reader for a content type, e.g.
+// "application/cbor" or "application/vnd.acme+json". Bodies of types
+// without a reader with the structured suffix +json or +xml are read as JSON
+// or XML.
 func (app *Application) RegisterReader(contentType string, reader Reader) error {
-	mt := parseMediaType(contentType)
-	if mt == mediaUnknown {
+	typ, ok := registeredMediaType(contentType)
+	if !ok {
 		return ErrContentType
 	}
-	app.readers[mt] = reader
-	app.hasReaders = true
+	if mt := builtinMediaType(typ); mt != mediaUnknown {
+		app.readers[mt] = reader
+		app.hasReaders = true
+		return nil
+	}
+	if app.mediaReaders == nil {
+		app.mediaReaders = make(map[string]Reader)
+	}
+	app.mediaReaders[typ] = reader
 	return nil
 }
 
-// RegisterWriter registers a response writer for a supported accept/content type.
+// RegisterWriter registers a response writer for a content type, e.g.
+// "text/csv" or "application/vnd.acme+json". Writers for types other than
+// the built-in ones take part in Accept negotiation after them, so that
+// they are chosen when asked for by name.
 func (app *Application) RegisterWriter(contentType string, writer Writer) error {
-	mt := parseMediaType(contentType)
-	if mt == mediaUnknown {
+	typ, ok := registeredMediaType(contentType)
+	if !ok {
 		return ErrContentType
 	}
-	app.writers[mt] = writer
-	app.hasWriters = true
+	if mt := builtinMediaType(typ); mt != mediaUnknown {
+		app.writers[mt] = writer
+		app.hasWriters = true
+		return nil
+	}
+
+	main, sub, _ := strings.Cut(typ, "/")
+	w := &mediaWriter{contentType: typ, typ: main, subtype: sub, write: writer}
+	writers := make([]*mediaWriter, 0, len(app.mediaWriters)+1)
+	for _, mw := range app.mediaWriters {
+		if mw.contentType != typ {
+			writers = append(writers, mw)
+		}
+	}
+	app.mediaWriters = append(writers, w)
+	app.accepts = &acceptCache{}
 	return nil
 }
 
+// registeredMediaType returns the lower case type of a content type given
+// to RegisterReader or RegisterWriter, and false if it is no concrete
+// "type/subtype".
+func registeredMediaType(contentType string) (string, bool) {
+	typ, _ := splitMediaType(contentType)
+	main, sub, ok := strings.Cut(typ, "/")
+	if !ok || main == "" || sub == "" || main == "*" || sub == "*" {
+		return "", false
+	}
+	return strings.ToLower(typ), true
+}
+
 // Use appends application middleware for subsequently registered routes.
+// Routes registered before get it too once Build is called.
 func (app *Application) Use(middleware ...Middleware) {
 	app.middleware = append(app.middleware, middleware...)
 }
@@ -140,49 +245,66 @@ func (app *Application) Group(prefix string, middleware ...Middleware) *RouteGro
 }
 
 // Handle registers a route for an arbitrary HTTP method.
-func (app *Application) Handle(method string, path string, next Next, middleware ...Middleware) {
-	app.addRoute(method, path, wrapNext(next, app.middleware, Chain(middleware)))
+func (app *Application) Handle(method string, path string, next Next, middleware ...Middleware) *Route {
+	return app.addRoute(nil, []string{method}, path, next, middleware, false)
+}
+
+// Match registers one route for several methods.
+func (app *Application) Match(methods []string, path string, next Next, middleware ...Middleware) *Route {
+	return app.addRoute(nil, methods, path, next, middleware, false)
+}
+
+// Any registers a route for every method, including custom ones like
+// PROPFIND. Routes registered for the request method take precedence.
+func (app *Application) Any(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(methodAny, path, next, middleware...)
 }
 
 // Get method
-func (app *Application) Get(path string, next Next) {
-	app.Handle(http.MethodGet, path, next)
+func (app *Application) Get(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodGet, path, next, middleware...)
 }
 
 // Head method
-func (app *Application) Head(path string, cb Next) {
-	app.Handle(http.MethodHead, path, cb)
+func (app *Application) Head(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodHead, path, next, middleware...)
 }
 
 // Post method
-func (app *Application) Post(path string, next Next) {
-	app.Handle(http.MethodPost, path, next)
+func (app *Application) Post(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodPost, path, next, middleware...)
 }
 
 // Put method
-func (app *Application) Put(path string, next Next) {
-	app.Handle(http.MethodPut, path, next)
+func (app *Application) Put(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodPut, path, next, middleware...)
 }
 
 // Patch method
-func (app *Application) Patch(path string, next Next) {
-	app.Handle(http.MethodPatch, path, next)
+func (app *Application) Patch(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodPatch, path, next, middleware...)
 }
 
 // Delete method
-func (app *Application) Delete(path string, next Next) {
-	app.Handle(http.MethodDelete, path, next)
+func (app *Application) Delete(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodDelete, path, next, middleware...)
 }
 
 // Options method
-func (app *Application) Options(path string, next Next) {
-	app.Handle(http.MethodOptions, path, next)
+func (app *Application) Options(path string, next Next, middleware ...Middleware) *Route {
+	return app.Handle(http.MethodOptions, path, next, middleware...)
 }
 
-func (app *Application) addRoute(method string, path string, next Next) {
+func (app *Application) addRoute(g *RouteGroup, methods []string, path string, next Next, middleware Chain, replace bool) *Route {
 
-	if method == "" {
-		panic("method must not be empty")
+	if len(methods) == 0 {
+		panic("methods must not be empty in path '" + path + "'")
+	}
+
+	for _, method := range methods {
+		if method == "" {
+			panic("method must not be empty")
+		}
 	}
 
 	if len(path) < 1 || path[0] != '/' {
@@ -193,26 +315,47 @@ func (app *Application) addRoute(method string, path string, next Next) {
 		panic("callback must not be nil")
 	}
 
-	if app.trees == nil {
-		app.trees = make(map[string]*node)
+	host, version := "", ""
+	if g != nil {
+		host, version = g.host, g.version
+	}
+
+	r := &Route{
+		app:        app,
+		group:      g,
+		host:       host,
+		version:    version,
+		path:       path,
+		params:     paramNames(path),
+		handler:    next,
+		middleware: append(Chain(nil), middleware...),
+		meta:       metaFor(app, g, nil),
 	}
 
-	root := app.trees[method]
+	app.update(func(t *routeTable) {
+		rt := t.scope(host, version)
 
-	if root == nil {
-		root = new(node)
-		app.trees[method] = root
-		if idx := methodRootIndex(method); idx >= 0 {
-			app.methodRoots[idx] = root
+		if replace {
+			for _, method := range methods {
+				if old := rt.remove(method, path); old != nil && old.name != "" && app.named[old.name] == old {
+					r.name = old.name
+					app.named[old.name] = r
+				}
+			}
+		}
+
+		r.chain = app.chainFor(g, r.middleware)
+		composed := wrapNext(next, r.chain)
+		for _, method := range methods {
+			rt.insert(method, r, composed)
 		}
-		app.globalAllowed = app.allowed("*", "")
-	}
 
-	root.addRoute(path, next)
+		if pc := uint32(countParams(path) + rt.hostParams); pc > app.params.max.Load() {
+			app.params.max.Store(pc)
+		}
+	})
 
-	if pc := countParams(path); pc > app.maxParams {
-		app.maxParams = pc
-	}
+	return r
 }
 
 // ServeFiles registers a route to serve static files from the specified file system under the given path pattern.
@@ -242,110 +385,273 @@ func (app *Application) ServeFiles(path string, root http.FileSystem) {
 	})
 }
 
+// Pre appends pre-routing middleware. It runs on every request before the
+// route lookup, so it may rewrite the request path or method, and it also
+// wraps the NotFound, MethodNotAllowed and OPTIONS responses.
+func (app *Application) Pre(middleware ...Middleware) {
+	app.pre = append(app.pre, middleware...)
+	app.preNext = wrapNext(app.dispatch, app.pre)
+}
+
+// Mount serves handler for every method and every path below prefix, with
+// the prefix stripped from the request path. Application middleware and the
+// given middleware wrap the handler. The handler may be another *Application.
+func (app *Application) Mount(prefix string, handler http.Handler, middleware ...Middleware) {
+	app.Group("").Mount(prefix, handler, middleware...)
+}
+
 // ServeHTTP w, r
 func (app *Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	defer app.recv(w, r)
 
-	rel := r.URL.Path
-	infoLogger := app.info
-	errLogger := app.err
+	c := createCtx(app, w, r, nil)
 
-	if root := app.rootForMethod(r.Method); root != nil {
+	var (
+		val any
+		err error
+	)
 
-		if next, params, _ := root.getValue(rel, app); next != nil {
+	if app.preNext != nil {
+		val, err = app.preNext(c)
+	} else {
+		val, err = app.dispatch(c)
+	}
 
-			c := createCtx(app, w, r, params)
-			val, err := next(c)
-			userID := c.UserId()
+	app.respond(c, val, err)
+}
 
-			if err != nil {
-				code, writeErr := app.handleError(c, err)
-				app.putParams(params)
-				releaseCtx(c)
-				if writeErr != nil && errLogger != nil {
-					errLogger.Printf("%s %s %d %s %s %d write error: %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, writeErr)
-				}
-				if errLogger != nil {
-					errLogger.Printf("%s %s %d %s %s %d %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, err)
-				}
+// dispatch looks up the route of the request, as left by the pre-routing
+// middleware, and calls it. Requests without a route are answered here.
+func (app *Application) dispatch(c *Ctx) (any, error) {
+	r := c.r
+	rel := r.URL.Path
 
-				return
+	table := app.table.Load()
+	rt, host := table.routerForHost(r.Host)
+
+	// Routes of the requested API version come first
+	var (
+		vrt  *router
+		vrel string
+	)
+	if host == nil && table.versions != nil {
+		if vrt, vrel = table.versionRouter(app, r); vrt != nil {
+			c.version, rel = vrt.version, vrel
+			if root, leaf, params, _, head := vrt.lookup(r.Method, vrel, app); leaf != nil {
+				return app.serveLeaf(c, vrt, nil, root, leaf, params, head)
 			}
+		}
+	}
 
-			if val != nil {
-				code := c.statusCode
-				if code == 0 {
-					code = http.StatusOK
-				}
-				mt := c.responseMediaType()
-				if !c.responseCommitted {
-					writeCodeByMedia(w, mt, code)
-				}
-				err := c.writeMedia(mt, val)
-				app.putParams(params)
-				releaseCtx(c)
-				if err != nil {
-					if errLogger != nil {
-						errLogger.Printf("%s %s %d %s %s %d write error: %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, err)
-					}
-					return
-				}
-
-				if infoLogger != nil {
-					infoLogger.Printf("%s %s %d %s %s %d", r.RemoteAddr, r.Host, userID, r.Method, rel, code)
-				}
+	root, leaf, params, tsr, head := rt.lookup(r.Method, rel, app)
 
-				if rel, ok := val.(IRelease); ok {
-					rel.Release()
-				}
-			} else {
-				code := c.statusCode
-				if code == 0 {
-					code = http.StatusNoContent
-				}
-				committed := c.responseCommitted
-				app.putParams(params)
-				releaseCtx(c)
-				if !committed {
-					w.WriteHeader(code)
-				}
+	if leaf != nil {
+		return app.serveLeaf(c, rt, host, root, leaf, params, head)
+	}
 
-				if infoLogger != nil {
-					infoLogger.Printf("%s %s %d %s %s %d", r.RemoteAddr, r.Host, userID, r.Method, rel, code)
-				}
-			}
+	if root != nil && app.redirect(c, r, root, tsr, params) {
+		return nil, nil
+	}
 
-			return
+	// Paths of the requested API version are answered by its router
+	var allow *allowSet
+	if vrt != nil {
+		if allow = vrt.allowed(vrel, r.Method); allow != nil {
+			rt, rel = vrt, vrel
 		}
 	}
+	if allow == nil {
+		allow = rt.allowed(rel, r.Method)
+	}
 
-	if r.Method == http.MethodOptions && app.cors != nil {
+	if r.Method == http.MethodOptions {
 		// Handle OPTIONS requests
-		if allow := app.allowed(rel, http.MethodOptions); len(allow) > 0 {
-			if origin := r.Header.Get("Origin"); origin != "" {
-				app.cors(w.Header().Set, origin, allow)
+		if allow != nil {
+			c.w.Header()["Allow"] = allow.header
+			if origin := r.Header.Get("Origin"); origin != "" && app.cors != nil {
+				// Reject preflights for methods the path does not serve
+				if m := r.Header.Get("Access-Control-Request-Method"); m != "" && !containsMethod(allow.methods, m) {
+					return nil, ErrMethodNotAllowed
+				}
+				app.cors(c.SetHeader, origin, allow.methods)
 			}
+			c.WriteHeader(http.StatusNoContent)
+			return nil, nil
 		}
-		w.WriteHeader(http.StatusNoContent)
-		return
 	}
 
-	if allow := app.allowed(rel, r.Method); len(allow) > 0 {
-		w.Header().Set("Allow", strings.Join(allow, ", "))
+	if allow != nil {
+		c.w.Header()["Allow"] = allow.header
+		if fb := findFallback(rt.methodNotAllowed, rel); fb != nil {
+			return fb.next(c)
+		}
 		if app.MethodNotAllowed != nil {
-			app.MethodNotAllowed.ServeHTTP(w, r)
-		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			app.MethodNotAllowed.ServeHTTP(c, r)
+			return nil, nil
+		}
+		return nil, ErrMethodNotAllowed
+	}
+
+	if vrt != nil {
+		if fb := findFallback(vrt.notFound, vrel); fb != nil {
+			return fb.next(c)
 		}
-		return
+	}
+	if fb := findFallback(rt.notFound, rel); fb != nil {
+		return fb.next(c)
 	}
 
 	if app.NotFound != nil {
-		app.NotFound.ServeHTTP(w, r)
+		app.NotFound.ServeHTTP(c, r)
+		return nil, nil
+	}
+	return nil, ErrNotFound
+}
+
+// serveLeaf calls the route of leaf, found in the root of rt. host is the
+// matched host router, if any.
+func (app *Application) serveLeaf(c *Ctx, rt *router, host *hostRouter, root, leaf *node, params *Params, head bool) (any, error) {
+	c.route, c.routeMethod = leaf.route, c.r.Method
+	if head {
+		c.w = &headResponseWriter{ResponseWriter: c.w}
+		c.routeMethod = http.MethodGet
+	} else if root == rt.anyRoot {
+		c.routeMethod = methodAny
+	}
+
+	if host != nil && host.hostParams > 0 {
+		params = host.captureParams(c.r.Host, params, app)
+	}
+
+	c.param = params
+	return leaf.next(c)
+}
+
+// containsMethod reports whether the Allow list contains method.
+func containsMethod(allow []string, method string) bool {
+	for _, m := range allow {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// respond writes the result of a request, logs it and releases c.
+func (app *Application) respond(c *Ctx, val any, err error) {
+	r := c.r
+	rel := r.URL.Path
+	w := c.w
+	params := c.param
+	userID := c.UserId()
+	infoLogger := app.info
+	errLogger := app.err
+
+	if hw, ok := w.(*headResponseWriter); ok {
+		defer hw.finish()
+	}
+
+	// Values are written in a media type the request accepts
+	if err == nil && val != nil && !c.responseCommitted && !c.acceptable() {
+		err = ErrNotAcceptable
+	}
+
+	if err != nil {
+		code, writeErr := app.handleError(c, err)
+		app.params.put(params)
+		releaseCtx(c)
+		if writeErr != nil && errLogger != nil {
+			errLogger.Printf("%s %s %d %s %s %d write error: %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, writeErr)
+		}
+		if errLogger != nil {
+			errLogger.Printf("%s %s %d %s %s %d %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, err)
+		}
+
+		return
+	}
+
+	if val != nil {
+		code := c.statusCode
+		if code == 0 {
+			code = http.StatusOK
+		}
+		mt := c.responseMediaType()
+		if !c.responseCommitted {
+			c.writeCodeByMedia(mt, code)
+		}
+		err := c.writeMedia(mt, val)
+		app.params.put(params)
+		releaseCtx(c)
+		if err != nil {
+			if errLogger != nil {
+				errLogger.Printf("%s %s %d %s %s %d write error: %v", r.RemoteAddr, r.Host, userID, r.Method, rel, code, err)
+			}
+			return
+		}
+
+		if infoLogger != nil {
+			infoLogger.Printf("%s %s %d %s %s %d", r.RemoteAddr, r.Host, userID, r.Method, rel, code)
+		}
+
+		if rel, ok := val.(IRelease); ok {
+			rel.Release()
+		}
 	} else {
-		http.NotFound(w, r)
+		code := c.statusCode
+		if code == 0 {
+			code = http.StatusNoContent
+		}
+		committed := c.responseCommitted
+		app.params.put(params)
+		releaseCtx(c)
+		if !committed {
+			w.WriteHeader(code)
+		}
+
+		if infoLogger != nil {
+			infoLogger.Printf("%s %s %d %s %s %d", r.RemoteAddr, r.Host, userID, r.Method, rel, code)
+		}
+	}
+}
+
+// redirect answers a request whose path did not match with a redirect to the
+// trailing-slash or cleaned variant of the path, if enabled and registered.
+func (app *Application) redirect(w http.ResponseWriter, r *http.Request, root *node, tsr bool, params *Params) bool {
+	app.params.put(params)
+
+	rel := r.URL.Path
+	if r.Method == http.MethodConnect || rel == "/" {
+		return false
+	}
+
+	// Moved Permanently, request with GET method
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet {
+		// Permanent Redirect, request with same method
+		code = http.StatusPermanentRedirect
+	}
+
+	if tsr && app.RedirectTrailingSlash {
+		if len(rel) > 1 && rel[len(rel)-1] == '/' {
+			r.URL.Path = rel[:len(rel)-1]
+		} else {
+			r.URL.Path = rel + "/"
+		}
+		http.Redirect(w, r, r.URL.String(), code)
+		return true
+	}
+
+	// Try to fix the request path
+	if app.RedirectFixedPath {
+		if fixedPath, found := root.findCaseInsensitivePath(cleanPath(rel), app.RedirectTrailingSlash); found && fixedPath != rel {
+			r.URL.Path = fixedPath
+			http.Redirect(w, r, r.URL.String(), code)
+			return true
+		}
 	}
+
+	return false
 }
 
 func (app *Application) handleError(c *Ctx, err error) (int, error) {
@@ -368,7 +674,10 @@ func (app *Application) handleError(c *Ctx, err error) (int, error) {
 	}
 
 	mt := c.responseMediaType()
-	writeCodeByMedia(c.w, mt, code)
+	c.writeCodeByMedia(mt, code)
+	if fields := errorFields(err); fields != nil && (mt == mediaJSON || mt == mediaXML || mt == mediaMsgPack) {
+		return code, c.writeMedia(mt, ErrorBody{Code: code, Message: err.Error(), Errors: fields})
+	}
 	return code, c.writeMedia(mt, err.Error())
 }
 
@@ -384,6 +693,64 @@ func wrapNext(next Next, chains ...Chain) Next {
 	return next
 }
 
+// chainFor flattens the middleware that wraps a route of group g: the
+// application middleware, the middleware of g and its parents from the
+// outermost group in, and the route's own middleware.
+func (app *Application) chainFor(g *RouteGroup, middleware Chain) Chain {
+	var groups []*RouteGroup
+	for ; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+
+	chain := make(Chain, 0, len(app.middleware)+len(middleware))
+	chain = appendMiddleware(chain, app.middleware)
+	for i := len(groups) - 1; i >= 0; i-- {
+		chain = appendMiddleware(chain, groups[i].middleware)
+	}
+	return appendMiddleware(chain, middleware)
+}
+
+func appendMiddleware(chain Chain, middleware Chain) Chain {
+	for _, mw := range middleware {
+		if mw != nil {
+			chain = append(chain, mw)
+		}
+	}
+	return chain
+}
+
+// Build composes the handler of every registered route again from its
+// callback and the middleware registered so far, and collects its metadata
+// again, so that middleware added with Use and options added with With
+// after a route apply to it as well. Applications are never
+// built implicitly: without Build, every route keeps the middleware of its
+// registration, whether served with ListenAndServe or used as an
+// http.Handler.
+func (app *Application) Build() {
+	app.update(func(t *routeTable) {
+		t.eachScope(func(rt *router) {
+			rt.build(app)
+		})
+	})
+}
+
+// build composes every leaf again, on a copy of the trees.
+func (rt *router) build(app *Application) {
+	rt.eachTree(func(method string, root *node) {
+		root = root.deepClone()
+		root.eachLeaf(nil, func(n *node, _ []*node) {
+			if r := n.route; r != nil {
+				r.chain = app.chainFor(r.group, r.middleware)
+				r.meta = metaFor(app, r.group, r.options)
+				n.next = wrapNext(r.handler, r.chain)
+			}
+		})
+		rt.setRoot(method, root)
+	})
+	rt.notFound = app.rebuildFallbacks(rt.notFound)
+	rt.methodNotAllowed = app.rebuildFallbacks(rt.methodNotAllowed)
+}
+
 func joinPaths(prefix, path string) string {
 	switch {
 	case prefix == "":
@@ -411,99 +778,155 @@ func (g *RouteGroup) Group(prefix string, middleware ...Middleware) *RouteGroup
 	}
 	child := &RouteGroup{
 		app:        g.app,
+		host:       g.host,
+		version:    g.version,
+		parent:     g,
 		prefix:     joinPaths(g.prefix, prefix),
-		middleware: append(append(Chain(nil), g.middleware...), middleware...),
+		middleware: append(Chain(nil), middleware...),
 	}
 	return child
 }
 
 // Handle registers a route on the group.
-func (g *RouteGroup) Handle(method string, path string, next Next, middleware ...Middleware) {
-	g.app.addRoute(method, joinPaths(g.prefix, path), wrapNext(next, g.app.middleware, g.middleware, Chain(middleware)))
+func (g *RouteGroup) Handle(method string, path string, next Next, middleware ...Middleware) *Route {
+	return g.app.addRoute(g, []string{method}, joinPaths(g.prefix, path), next, middleware, false)
+}
+
+// Match registers one route for several methods on the group.
+func (g *RouteGroup) Match(methods []string, path string, next Next, middleware ...Middleware) *Route {
+	return g.app.addRoute(g, methods, joinPaths(g.prefix, path), next, middleware, false)
+}
+
+// Any registers a route for every method on the group.
+func (g *RouteGroup) Any(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(methodAny, path, next, middleware...)
 }
 
 // Get registers a GET route on the group.
-func (g *RouteGroup) Get(path string, next Next) {
-	g.Handle(http.MethodGet, path, next)
+func (g *RouteGroup) Get(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodGet, path, next, middleware...)
 }
 
 // Head registers a HEAD route on the group.
-func (g *RouteGroup) Head(path string, next Next) {
-	g.Handle(http.MethodHead, path, next)
+func (g *RouteGroup) Head(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodHead, path, next, middleware...)
 }
 
 // Post registers a POST route on the group.
-func (g *RouteGroup) Post(path string, next Next) {
-	g.Handle(http.MethodPost, path, next)
+func (g *RouteGroup) Post(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodPost, path, next, middleware...)
 }
 
 // Put registers a PUT route on the group.
-func (g *RouteGroup) Put(path string, next Next) {
-	g.Handle(http.MethodPut, path, next)
+func (g *RouteGroup) Put(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodPut, path, next, middleware...)
 }
 
 // Patch registers a PATCH route on the group.
-func (g *RouteGroup) Patch(path string, next Next) {
-	g.Handle(http.MethodPatch, path, next)
+func (g *RouteGroup) Patch(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodPatch, path, next, middleware...)
 }
 
 // Delete registers a DELETE route on the group.
-func (g *RouteGroup) Delete(path string, next Next) {
-	g.Handle(http.MethodDelete, path, next)
+func (g *RouteGroup) Delete(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodDelete, path, next, middleware...)
 }
 
 // Options registers an OPTIONS route on the group.
-func (g *RouteGroup) Options(path string, next Next) {
-	g.Handle(http.MethodOptions, path, next)
+func (g *RouteGroup) Options(path string, next Next, middleware ...Middleware) *Route {
+	return g.Handle(http.MethodOptions, path, next, middleware...)
 }
 
-func (app *Application) allowed(path, reqMethod string) []string {
+// Mount serves handler for every method and every path below prefix on the
+// group, with the group prefix and prefix stripped from the request path.
+// Group middleware and the given middleware wrap the handler.
+func (g *RouteGroup) Mount(prefix string, handler http.Handler, middleware ...Middleware) {
+	if prefix != "" && prefix[0] != '/' {
+		panic("mount prefix must begin with '/' in path '" + prefix + "'")
+	}
 
-	allowed := make([]string, 0, 9)
+	if handler == nil {
+		panic("mount handler must not be nil")
+	}
 
-	if path == "*" { // server-wide
-		// empty method is used for internal calls to refresh the cache
-		if reqMethod == "" {
-			for method := range app.trees {
-				if method == http.MethodOptions {
-					continue
-				}
-				// Add request method to list of allowed methods
-				allowed = append(allowed, method)
-			}
+	prefix = strings.TrimSuffix(prefix, "/")
+	next := mountNext(handler)
+
+	if joinPaths(g.prefix, prefix) != "" {
+		g.Any(prefix, next, middleware...)
+	}
+	g.Any(prefix+"/*"+mountParam, next, middleware...)
+}
+
+// mountNext adapts handler to a route callback which passes the request on
+// with the mount prefix stripped from its path.
+func mountNext(handler http.Handler) Next {
+	return func(c *Ctx) (any, error) {
+		r := c.r
+
+		path := c.Param(mountParam)
+		if path == "" {
+			path = "/"
+		}
+		prefix := strings.TrimSuffix(r.URL.Path, strings.TrimPrefix(path, "/"))
+		prefix = strings.TrimSuffix(prefix, "/")
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		if rp := strings.TrimPrefix(r.URL.RawPath, prefix); rp != r.URL.RawPath && rp != "" {
+			r2.URL.RawPath = rp
 		} else {
-			return app.globalAllowed
+			r2.URL.RawPath = ""
 		}
-	} else { // specific path
-		for method := range app.trees {
-			// Skip the requested method - we already tried this one
-			if method == reqMethod || method == http.MethodOptions {
-				continue
-			}
 
-			cb, _, _ := app.trees[method].getValue(path, nil)
-			if cb != nil {
-				// Add request method to list of allowed methods
-				allowed = append(allowed, method)
-			}
+		handler.ServeHTTP(c, r2)
+
+		// Like net/http, a handler that wrote nothing answers 200
+		if !c.responseCommitted {
+			c.WriteHeader(http.StatusOK)
 		}
+		return nil, nil
 	}
+}
 
-	if len(allowed) > 0 {
-
-		allowed = append(allowed, http.MethodOptions)
+// lookup returns the leaf for method and path, along with the tree it
+// was searched in. HEAD requests without a HEAD route fall back to the GET
+// route of the path, which is reported by head.
+func (rt *router) lookup(method, path string, app *Application) (root *node, leaf *node, ps *Params, tsr bool, head bool) {
+	if root = rt.rootForMethod(method); root != nil {
+		if leaf, ps, tsr = root.getLeaf(path, app); leaf != nil {
+			return
+		}
+	}
 
-		sort.Strings(allowed)
+	if method == http.MethodHead && rt.methodRoots[0] != nil {
+		root = rt.methodRoots[0]
+		if leaf, ps, tsr = root.getLeaf(path, app); leaf != nil {
+			head = true
+			return
+		}
 	}
 
-	return allowed
+	if rt.anyRoot != nil {
+		var anyTSR bool
+		if leaf, ps, anyTSR = rt.anyRoot.getLeaf(path, app); leaf != nil {
+			return rt.anyRoot, leaf, ps, false, false
+		}
+		if root == nil {
+			root, tsr = rt.anyRoot, anyTSR
+		}
+	}
+	return
 }
 
-func (app *Application) rootForMethod(method string) *node {
+func (rt *router) rootForMethod(method string) *node {
 	if idx := methodRootIndex(method); idx >= 0 {
-		return app.methodRoots[idx]
+		return rt.methodRoots[idx]
 	}
-	return app.trees[method]
+	return rt.trees[method]
 }
 
 // ListenAndServe Serve with options on addr
@@ -573,9 +996,114 @@ func (app *Application) serve(listener net.Listener, fns ...func(*http.Server))
 	return nil
 }
 
-// Inspect method
+// Routes returns every registered route, ordered by host, path and method.
+func (app *Application) Routes() []RouteInfo {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	routes := make([]RouteInfo, 0, 16)
+	app.table.Load().eachRouter(func(rt *router) {
+		routes = rt.appendRoutes(routes)
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Version != routes[j].Version {
+			return routes[i].Version < routes[j].Version
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func (rt *router) appendRoutes(routes []RouteInfo) []RouteInfo {
+	rt.eachTree(func(method string, root *node) {
+		// A route with optional params has a leaf per expansion; it is
+		// reported once, with the constraints of its longest expansion.
+		seen := make(map[*Route]int)
+		root.eachLeaf(nil, func(n *node, constrained []*node) {
+			if i, ok := seen[n.route]; ok {
+				routes[i].Constraints = constraintInfo(constrained)
+				return
+			}
+			info := RouteInfo{Method: method, Host: rt.host, Version: rt.version}
+			if r := n.route; r != nil {
+				seen[r] = len(routes)
+				info.Path = r.path
+				info.Name = r.name
+				info.Params = r.params
+				info.Middleware = len(r.chain)
+				info.Chain = middlewareNames(r.chain)
+				info.Meta = r.meta
+			}
+			info.Constraints = constraintInfo(constrained)
+			routes = append(routes, info)
+		})
+	})
+	return routes
+}
+
+func constraintInfo(constrained []*node) []ParamConstraint {
+	var constraints []ParamConstraint
+	for _, p := range constrained {
+		constraints = append(constraints, ParamConstraint{
+			Param:    p.key,
+			Pattern:  p.constraint.pattern,
+			Rejected: p.constraint.rejected.Load(),
+		})
+	}
+	return constraints
+}
+
+// middlewareNames names the functions of a chain after the function that
+// created them, e.g. "web.RequestID" for the closure returned by RequestID.
+func middlewareNames(chain Chain) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+	names := make([]string, len(chain))
+	for i, mw := range chain {
+		name := "?"
+		if fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer()); fn != nil {
+			name = fn.Name()
+			if k := strings.LastIndexByte(name, '/'); k >= 0 {
+				name = name[k+1:]
+			}
+			if k := strings.Index(name, ".func"); k >= 0 && k+5 < len(name) && isUintParam(name[k+5:k+6]) {
+				name = name[:k]
+			}
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// Inspect returns the route table as aligned text with one route per line.
 func (app *Application) Inspect() string {
-	return ""
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tPARAMS\tMIDDLEWARE\tREJECTED")
+	for _, r := range app.Routes() {
+		params := "-"
+		if len(r.Params) > 0 {
+			params = strings.Join(r.Params, ",")
+		}
+		rejected := "-"
+		if len(r.Constraints) > 0 {
+			var n uint64
+			for _, c := range r.Constraints {
+				n += c.Rejected
+			}
+			rejected = strconv.FormatUint(n, 10)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", r.Method, r.Host+r.Path, params, r.Middleware, rejected)
+	}
+	tw.Flush()
+	return sb.String()
 }
 
 // Logf write info log
@@ -592,20 +1120,90 @@ func (app *Application) Errf(format string, v ...any) {
 	}
 }
 
-func (app *Application) getParams() *Params {
-	ps := app.paramsPool.Get().(*Params)
-	if uint16(cap(*ps)) < app.maxParams {
-		*ps = make(Params, 0, app.maxParams)
-	} else {
+// paramsPool recycles the Params of matched routes, each with room for max
+// values. The zero value is ready to use.
+type paramsPool struct {
+	pool sync.Pool
+	max  atomic.Uint32
+}
+
+func (p *paramsPool) get() *Params {
+	n := max(p.max.Load(), 1)
+	if ps, ok := p.pool.Get().(*Params); ok && uint32(cap(*ps)) >= n {
 		*ps = (*ps)[0:0]
+		return ps
 	}
-	return ps
+	ps := make(Params, 0, n)
+	return &ps
 }
 
-func (app *Application) putParams(ps *Params) {
+func (p *paramsPool) put(ps *Params) {
 	if ps != nil {
-		app.paramsPool.Put(ps)
+		p.pool.Put(ps)
+	}
+}
+
+// headResponseWriter serves a HEAD request with a GET route. It discards the
+// body and reports its length in Content-Length, which requires holding back
+// the status line until the route has finished.
+type headResponseWriter struct {
+	http.ResponseWriter
+	code      int
+	n         int
+	committed bool
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+// Flush commits the status line without a length, as the body so far is
+// not the whole body.
+func (w *headResponseWriter) Flush() {
+	w.commit(false)
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headResponseWriter) finish() {
+	w.commit(true)
+}
+
+func (w *headResponseWriter) commit(done bool) {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	h := w.ResponseWriter.Header()
+	if done && w.n > 0 && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.Itoa(w.n))
 	}
+	w.ResponseWriter.WriteHeader(code)
 }
 
 func (app *Application) recv(w http.ResponseWriter, r *http.Request) {
@@ -1,10 +1,14 @@
 package web
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHttpGet(t *testing.T) {
@@ -348,7 +352,464 @@ func TestMethodNotAllowed(t *testing.T) {
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Fatalf("Expected status code 405, but got %d", rec.Code)
 	}
-	if got := rec.Header().Get("Allow"); got != "GET, OPTIONS" {
-		t.Fatalf("Expected Allow header %q, but got %q", "GET, OPTIONS", got)
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Fatalf("Expected Allow header %q, but got %q", "GET, HEAD, OPTIONS", got)
+	}
+}
+
+func TestAutomaticHeadAndOptions(t *testing.T) {
+	app := New()
+	app.Get("/users", func(c *Ctx) (any, error) {
+		c.SetHeader("X-Total", "2")
+		return "ok", nil
+	})
+	app.Head("/files", func(c *Ctx) (any, error) {
+		c.SetHeader("X-Explicit", "1")
+		return nil, nil
+	})
+	app.Get("/files", func(c *Ctx) (any, error) {
+		return "files", nil
+	})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code 200, but got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("Expected empty body, but got %q", rec.Body.String())
+	}
+	if rec.Header().Get("X-Total") != "2" || rec.Header().Get("Content-Length") == "" {
+		t.Fatalf("Expected GET headers and Content-Length, got %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/files", nil))
+	if rec.Header().Get("X-Explicit") != "1" {
+		t.Fatalf("Expected explicit HEAD route to win, got %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/users", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status code 204, but got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Fatalf("Expected Allow header %q, but got %q", "GET, HEAD, OPTIONS", got)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status code 404, but got %d", rec.Code)
+	}
+}
+
+func TestPreMiddlewareWrapsUnmatchedRequests(t *testing.T) {
+	app := New()
+	statuses := []int{}
+	app.Pre(
+		RequestID("", func() string { return "pre" }),
+		AccessLog(func(c *Ctx, status int, d time.Duration, err error) {
+			statuses = append(statuses, status)
+		}),
+		func(next Next) Next {
+			return func(c *Ctx) (any, error) {
+				if m := c.GetHeader("X-HTTP-Method-Override"); m != "" {
+					c.Request().Method = m
+				}
+				c.Request().URL.Path = strings.TrimPrefix(c.Path(), "/v1")
+				if c.Path() == "/blocked" {
+					return nil, ErrForbidden
+				}
+				return next(c)
+			}
+		},
+	)
+	app.Get("/users", func(c *Ctx) (any, error) {
+		return "list", nil
+	})
+	app.Delete("/users", func(c *Ctx) (any, error) {
+		return nil, nil
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		override string
+		status   int
+	}{
+		{name: "rewritten path", method: http.MethodGet, path: "/v1/users", status: http.StatusOK},
+		{name: "rewritten method", method: http.MethodPost, path: "/users", override: http.MethodDelete, status: http.StatusNoContent},
+		{name: "not found", method: http.MethodGet, path: "/missing", status: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPut, path: "/users", status: http.StatusMethodNotAllowed},
+		{name: "options", method: http.MethodOptions, path: "/users", status: http.StatusNoContent},
+		{name: "short circuit", method: http.MethodGet, path: "/blocked", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses = statuses[:0]
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.override != "" {
+				req.Header.Set("X-HTTP-Method-Override", tt.override)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if got := rec.Header().Get(DefaultRequestIDHeader); got != "pre" {
+				t.Fatalf("expected request id header, got %q", got)
+			}
+			if len(statuses) != 1 || statuses[0] != tt.status {
+				t.Fatalf("expected access log status %d, got %v", tt.status, statuses)
+			}
+		})
+	}
+}
+
+func TestBuildAppliesLateMiddleware(t *testing.T) {
+	tag := func(name string) Middleware {
+		return func(next Next) Next {
+			return func(c *Ctx) (any, error) {
+				c.Header().Add("X-Chain", name)
+				return next(c)
+			}
+		}
+	}
+
+	app := New()
+	api := app.Group("/api")
+	v1 := api.Group("/v1")
+	v1.Handle(http.MethodGet, "/users", func(c *Ctx) (any, error) {
+		return nil, nil
+	}, tag("route"))
+	app.Use(tag("app"))
+	api.Use(tag("api"))
+	v1.Use(tag("v1"))
+
+	serve := func() []string {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+		return rec.Header().Values("X-Chain")
+	}
+
+	if got := serve(); !reflect.DeepEqual(got, []string{"route"}) {
+		t.Fatalf("expected registration-time chain before Build, got %v", got)
+	}
+
+	app.Build()
+
+	if got, want := serve(), []string{"app", "api", "v1", "route"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected chain %v after Build, got %v", want, got)
+	}
+
+	routes := app.Routes()
+	if len(routes) != 1 || routes[0].Middleware != 4 || len(routes[0].Chain) != 4 {
+		t.Fatalf("expected route table to report the built chain, got %+v", routes)
+	}
+}
+
+func TestServeDoesNotBuild(t *testing.T) {
+	app := New()
+	app.Get("/health", func(c *Ctx) (any, error) { return "ok", nil })
+	app.Use(func(next Next) Next {
+		return func(c *Ctx) (any, error) { return nil, ErrUnauthorized }
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- app.serve(l, func(*http.Server) { close(ready) }) }()
+	<-ready
+	defer func() {
+		app.Shutdown(context.Background())
+		<-done
+	}()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the registration-time chain when served, got %d", res.StatusCode)
+	}
+}
+
+func TestMatchAndAny(t *testing.T) {
+	app := New()
+	app.Match([]string{http.MethodGet, http.MethodPost}, "/hooks", func(c *Ctx) (any, error) {
+		return c.Method(), nil
+	}).Name("hooks")
+	dav := app.Group("/dav")
+	dav.Any("/*path", func(c *Ctx) (any, error) {
+		return "any " + c.Method(), nil
+	})
+	dav.Get("/status", func(c *Ctx) (any, error) {
+		return "status", nil
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/hooks", status: http.StatusOK, body: "\"GET\"\n"},
+		{method: http.MethodPost, path: "/hooks", status: http.StatusOK, body: "\"POST\"\n"},
+		{method: http.MethodPut, path: "/hooks", status: http.StatusMethodNotAllowed},
+		{method: "PROPFIND", path: "/dav/docs", status: http.StatusOK, body: "\"any PROPFIND\"\n"},
+		{method: http.MethodDelete, path: "/dav/docs", status: http.StatusOK, body: "\"any DELETE\"\n"},
+		{method: http.MethodGet, path: "/dav/status", status: http.StatusOK, body: "\"status\"\n"},
+		{method: http.MethodPost, path: "/dav/status", status: http.StatusOK, body: "\"any POST\"\n"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Fatalf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.body, rec.Body.String())
+		}
+	}
+
+	methods := []string{}
+	for _, r := range app.Routes() {
+		methods = append(methods, r.Method+" "+r.Path)
+	}
+	want := []string{"* /dav/*path", "GET /dav/status", "GET /hooks", "POST /hooks"}
+	if !reflect.DeepEqual(methods, want) {
+		t.Fatalf("expected routes %v, got %v", want, methods)
+	}
+
+	if !app.Remove(http.MethodGet, "/hooks") {
+		t.Fatalf("expected GET /hooks to be removed")
+	}
+	if _, err := app.URL("hooks"); err != nil {
+		t.Fatalf("expected name to stay while POST /hooks is registered: %v", err)
+	}
+	if !app.Remove("*", "/dav/*path") {
+		t.Fatalf("expected Any route to be removed")
+	}
+	if code := serveStatus(app, "PROPFIND", "/dav/docs"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 after removing Any route, got %d", code)
+	}
+}
+
+func TestRoutesAndInspect(t *testing.T) {
+	app := New()
+	app.Use(RequestID("", nil))
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Post("/users", func(c *Ctx) (any, error) { return nil, nil })
+	app.ServeFiles("/static/*filepath", http.Dir("."))
+
+	want := []RouteInfo{
+		{Method: http.MethodGet, Path: "/static/*filepath", Params: []string{"filepath"}, Middleware: 1, Chain: []string{"web.RequestID"}},
+		{Method: http.MethodPost, Path: "/users", Middleware: 1, Chain: []string{"web.RequestID"}},
+		{Method: http.MethodGet, Path: "/users/:id", Params: []string{"id"}, Middleware: 1, Chain: []string{"web.RequestID"}},
+	}
+	if got := app.Routes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected routes: got %+v want %+v", got, want)
+	}
+
+	table := app.Inspect()
+	for _, line := range []string{
+		"METHOD  PATH               PARAMS    MIDDLEWARE  REJECTED\n",
+		"GET     /users/:id         id        1           -\n",
+		"POST    /users             -         1           -\n",
+	} {
+		if !strings.Contains(table, line) {
+			t.Fatalf("expected route table to contain %q, got:\n%s", line, table)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	app := New()
+	app.RedirectTrailingSlash = true
+	app.Get("/users", func(c *Ctx) (any, error) { return "ok", nil })
+	app.Post("/teams/", func(c *Ctx) (any, error) { return "ok", nil })
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{method: http.MethodGet, path: "/users/", code: http.StatusMovedPermanently, location: "/users"},
+		{method: http.MethodGet, path: "/users/?page=2", code: http.StatusMovedPermanently, location: "/users?page=2"},
+		{method: http.MethodPost, path: "/teams", code: http.StatusPermanentRedirect, location: "/teams/"},
+		{method: http.MethodGet, path: "/Users/", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Fatalf("%s %s: expected Location %q, got %q", tt.method, tt.path, tt.location, got)
+		}
+	}
+}
+
+func TestRedirectFixedPath(t *testing.T) {
+	app := New()
+	app.RedirectFixedPath = true
+	app.RedirectTrailingSlash = true
+	app.Get("/users/:id/books", func(c *Ctx) (any, error) { return "ok", nil })
+	app.Put("/static/*filepath", func(c *Ctx) (any, error) { return "ok", nil })
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{method: http.MethodGet, path: "/USERS/Ab/Books", code: http.StatusMovedPermanently, location: "/users/Ab/books"},
+		{method: http.MethodGet, path: "//users/../users/7/books/", code: http.StatusMovedPermanently, location: "/users/7/books"},
+		{method: http.MethodPut, path: "/Static/CSS/app.css", code: http.StatusPermanentRedirect, location: "/static/CSS/app.css"},
+		{method: http.MethodGet, path: "/users/7", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.URL.Path = tt.path
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Fatalf("%s %s: expected Location %q, got %q", tt.method, tt.path, tt.location, got)
+		}
+	}
+}
+
+func TestMountStripsPrefixAndAppliesMiddleware(t *testing.T) {
+	app := New()
+	calls := 0
+	count := func(next Next) Next {
+		return func(c *Ctx) (any, error) {
+			calls++
+			return next(c)
+		}
+	}
+
+	legacy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(r.Method))
+	})
+	app.Mount("/legacy", legacy)
+
+	sub := New()
+	sub.Get("/users/:id", func(c *Ctx) (any, error) { return "sub:" + c.Param("id"), nil })
+	app.Group("/api", count).Mount("/v1/", sub)
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+		xpath  string
+	}{
+		{method: http.MethodGet, path: "/legacy", code: http.StatusAccepted, body: "GET", xpath: "/"},
+		{method: http.MethodDelete, path: "/legacy/a/b", code: http.StatusAccepted, body: "DELETE", xpath: "/a/b"},
+		{method: "PROPFIND", path: "/legacy/dav", code: http.StatusAccepted, body: "PROPFIND", xpath: "/dav"},
+		{method: http.MethodOptions, path: "/legacy", code: http.StatusAccepted, body: "OPTIONS", xpath: "/"},
+		{method: http.MethodGet, path: "/api/v1/users/7", code: http.StatusOK, body: `"sub:7"` + "\n"},
+		{method: http.MethodGet, path: "/api/v1/missing", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Fatalf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.body, rec.Body.String())
+		}
+		if got := rec.Header().Get("X-Path"); got != tt.xpath {
+			t.Fatalf("%s %s: expected stripped path %q, got %q", tt.method, tt.path, tt.xpath, got)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected group middleware to run for both mounted requests, got %d", calls)
+	}
+}
+
+func TestUnmatchedRequestsUseErrorHandler(t *testing.T) {
+	app := New()
+	app.SetErrorHandler(JSONErrorHandler(false))
+	app.SetCORS(func(set func(key string, value string), origin string, allow []string) {
+		set("Access-Control-Allow-Origin", origin)
+	})
+	app.Get("/users", func(c *Ctx) (any, error) { return nil, nil })
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		preflight string
+		status    int
+		body      string
+	}{
+		{name: "not found", method: http.MethodGet, path: "/missing", status: http.StatusNotFound, body: "{\"code\":404,\"message\":\"NOTFOUND\"}\n"},
+		{name: "method not allowed", method: http.MethodPut, path: "/users", status: http.StatusMethodNotAllowed, body: "{\"code\":405,\"message\":\"METHODNOTALLOWED\"}\n"},
+		{name: "preflight for unknown path", method: http.MethodOptions, path: "/missing", preflight: http.MethodGet, status: http.StatusNotFound, body: "{\"code\":404,\"message\":\"NOTFOUND\"}\n"},
+		{name: "preflight for other method", method: http.MethodOptions, path: "/users", preflight: http.MethodDelete, status: http.StatusMethodNotAllowed, body: "{\"code\":405,\"message\":\"METHODNOTALLOWED\"}\n"},
+		{name: "preflight", method: http.MethodOptions, path: "/users", preflight: http.MethodGet, status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.preflight != "" {
+				req.Header.Set("Origin", "https://example.com")
+				req.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.body {
+				t.Fatalf("expected body %q, got %q", tt.body, got)
+			}
+			allowed := rec.Header().Get("Access-Control-Allow-Origin") != ""
+			if allowed != (tt.status == http.StatusNoContent && tt.preflight != "") {
+				t.Fatalf("unexpected CORS headers: %v", rec.Header())
+			}
+		})
+	}
+
+	// Without an error handler the error is written in the negotiated media type
+	app = New()
+	app.Get("/users", func(c *Ctx) (any, error) { return nil, nil })
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "\"NOTFOUND\"\n" {
+		t.Fatalf("expected JSON 404, got %d %q", rec.Code, rec.Body.String())
 	}
 }
@@ -52,6 +52,27 @@ func BenchmarkServeHTTPStaticJSON(b *testing.B) {
 	}
 }
 
+func BenchmarkServeHTTPStaticMsgPack(b *testing.B) {
+	app := New()
+	out := struct {
+		Ok bool `json:"ok"`
+	}{Ok: true}
+	app.Get("/v1/ping", func(c *Ctx) (any, error) {
+		return out, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/ping", nil)
+	req.Header.Set("Accept", "application/x-msgpack")
+	w := newBenchResponseWriter()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		app.ServeHTTP(w, req)
+	}
+}
+
 func BenchmarkServeHTTPPathParamJSON(b *testing.B) {
 	app := New()
 	type out struct {
@@ -110,6 +131,39 @@ func BenchmarkServeHTTPNoContent(b *testing.B) {
 	}
 }
 
+func BenchmarkServeHTTPOptions(b *testing.B) {
+	app := New()
+	app.Get("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Put("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Delete("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Build()
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/user/42", nil)
+	w := newBenchResponseWriter()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		app.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkRouterAllowed(b *testing.B) {
+	app := New()
+	app.Get("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Put("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Delete("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Build()
+	rt := &app.table.Load().router
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rt.allowed("/v1/user/42", http.MethodPost)
+	}
+}
+
 func BenchmarkServeHTTPManualWrite(b *testing.B) {
 	app := New()
 	payload := []byte(`{"ok":true}`)
@@ -180,6 +234,35 @@ func BenchmarkTryParseBodyJSON(b *testing.B) {
 	}
 }
 
+func BenchmarkTryParseBodyMsgPack(b *testing.B) {
+	type reqBody struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Active bool   `json:"active"`
+	}
+
+	payload, err := MarshalMsgPack(reqBody{ID: 123, Name: "sam", Active: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/user", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/x-msgpack")
+		rec := httptest.NewRecorder()
+		c := createCtx(nil, rec, req, nil)
+
+		var body reqBody
+		if err := c.TryParseBody(&body); err != nil {
+			b.Fatalf("TryParseBody failed: %v", err)
+		}
+
+		releaseCtx(c)
+	}
+}
+
 func BenchmarkTryParseJSONBodyFast(b *testing.B) {
 	payload := []byte(`{"id":123,"name":"sam","active":true}`)
 
@@ -470,7 +553,7 @@ func BenchmarkTreeGetValueParam(b *testing.B) {
 	root := new(node)
 	root.addRoute("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
 	app := New()
-	app.maxParams = 1
+	app.params.max.Store(1)
 
 	path := "/v1/user/123456"
 	b.ReportAllocs()
@@ -490,7 +573,7 @@ func BenchmarkTreeGetValueCatchAll(b *testing.B) {
 	root := new(node)
 	root.addRoute("/static/*filepath", func(c *Ctx) (any, error) { return nil, nil })
 	app := New()
-	app.maxParams = 1
+	app.params.max.Store(1)
 
 	path := "/static/css/app.css"
 	b.ReportAllocs()
@@ -510,7 +593,7 @@ func BenchmarkTreeGetValueParamPooled(b *testing.B) {
 	root := new(node)
 	root.addRoute("/v1/user/:id", func(c *Ctx) (any, error) { return nil, nil })
 	app := New()
-	app.maxParams = 1
+	app.params.max.Store(1)
 
 	path := "/v1/user/123456"
 	b.ReportAllocs()
@@ -523,7 +606,7 @@ func BenchmarkTreeGetValueParamPooled(b *testing.B) {
 		if (*ps)[0].Key != "id" || (*ps)[0].Value != "123456" {
 			b.Fatalf("unexpected param: %+v", (*ps)[0])
 		}
-		app.putParams(ps)
+		app.params.put(ps)
 	}
 }
 
@@ -531,7 +614,7 @@ func BenchmarkTreeGetValueCatchAllPooled(b *testing.B) {
 	root := new(node)
 	root.addRoute("/static/*filepath", func(c *Ctx) (any, error) { return nil, nil })
 	app := New()
-	app.maxParams = 1
+	app.params.max.Store(1)
 
 	path := "/static/css/app.css"
 	b.ReportAllocs()
@@ -544,6 +627,6 @@ func BenchmarkTreeGetValueCatchAllPooled(b *testing.B) {
 		if (*ps)[0].Key != "filepath" || (*ps)[0].Value != "/css/app.css" {
 			b.Fatalf("unexpected param: %+v", (*ps)[0])
 		}
-		app.putParams(ps)
+		app.params.put(ps)
 	}
 }
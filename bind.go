@@ -0,0 +1,255 @@
+package web
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// bindSources are the tags read by Bind, in order of precedence.
+var bindSources = [...]string{"path", "query", "header", "cookie", "form"}
+
+const (
+	bindPath = iota
+	bindQuery
+	bindHeader
+	bindCookie
+	bindForm
+)
+
+// bindKinds name the values TryParse accepts for a kind, one and many.
+var bindKinds = map[reflect.Kind][2]string{
+	reflect.String:  {"a string", "strings"},
+	reflect.Int:     {"an integer", "integers"},
+	reflect.Int8:    {"an integer", "integers"},
+	reflect.Int16:   {"an integer", "integers"},
+	reflect.Int32:   {"an integer", "integers"},
+	reflect.Int64:   {"an integer", "integers"},
+	reflect.Uint:    {"a non-negative integer", "non-negative integers"},
+	reflect.Uint8:   {"a non-negative integer", "non-negative integers"},
+	reflect.Uint16:  {"a non-negative integer", "non-negative integers"},
+	reflect.Uint32:  {"a non-negative integer", "non-negative integers"},
+	reflect.Uint64:  {"a non-negative integer", "non-negative integers"},
+	reflect.Float32: {"a number", "numbers"},
+	reflect.Float64: {"a number", "numbers"},
+	reflect.Bool:    {"a boolean", "booleans"},
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// bindField is a struct field filled by Bind.
+type bindField struct {
+	index   []int
+	source  int
+	name    string
+	pointer string // of the FieldError reported for the field
+	message string
+	alloc   bool // the field is a pointer, set when a value is present
+	text    bool // the field is parsed with UnmarshalText
+}
+
+// bindType holds the fields Bind fills for a struct type.
+type bindType struct {
+	fields []bindField
+	form   bool
+	err    error
+}
+
+var bindTypes sync.Map // reflect.Type -> *bindType
+
+// Bind fills the struct v points to from the request. The body is decoded
+// with TryParseBody unless the request has none or is a form, then fields
+// tagged path, query, header, cookie or form take the named path param,
+// query value, header, cookie or form value, parsed with TryParse or
+// UnmarshalText, e.g.
+//
+//	type Request struct {
+//		ID     uint64   `path:"id"`
+//		Page   int      `query:"page"`
+//		Tags   []string `query:"tag"`
+//		Tenant string   `header:"X-Tenant"`
+//		Name   string   `json:"name"`
+//	}
+//
+// Repeated values of a field fill slices like a comma separated list. Values
+// that do not parse fail with an *InvalidError naming them all, e.g. with
+// the pointer "/query/page". Pointer fields are set only if a value is
+// present. The struct is then checked with Validate if
+// Application.AutoValidate is set.
+func (c *Ctx) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Bind: %T is not a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+
+	bt := bindTypeOf(rv.Type())
+	if bt.err != nil {
+		return bt.err
+	}
+
+	r := c.r
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 && !c.IsForm() {
+		if err := c.parseBody(v); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	if bt.form && r.Form == nil {
+		// Parse errors leave the form empty, as with Request.FormValue
+		r.ParseMultipartForm(32 << 20)
+	}
+
+	var fields []FieldError
+	for i := range bt.fields {
+		f := &bt.fields[i]
+		val := c.bindValue(f)
+		if val == "" {
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		dst := fv
+		if f.alloc {
+			dst = reflect.New(fv.Type().Elem()).Elem()
+		}
+
+		var err error
+		if f.text {
+			err = dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+		} else {
+			err = TryParse(val, dst.Addr().Interface())
+		}
+		if err != nil {
+			fields = append(fields, FieldError{Pointer: f.pointer, Message: f.message})
+			continue
+		}
+		if f.alloc {
+			fv.Set(dst.Addr())
+		}
+	}
+
+	if fields != nil {
+		return NewInvalidError(fields)
+	}
+	return c.autoValidate(v)
+}
+
+// bindValue returns the value of f in the request, with repeated values
+// joined by commas.
+func (c *Ctx) bindValue(f *bindField) string {
+	var vals []string
+	switch f.source {
+	case bindPath:
+		return c.Param(f.name)
+	case bindQuery:
+		vals = c.QueryValues()[f.name]
+	case bindHeader:
+		vals = c.r.Header.Values(f.name)
+	case bindCookie:
+		if cookie, err := c.r.Cookie(f.name); err == nil {
+			return cookie.Value
+		}
+		return ""
+	case bindForm:
+		vals = c.r.Form[f.name]
+	}
+
+	switch len(vals) {
+	case 0:
+		return ""
+	case 1:
+		return vals[0]
+	default:
+		return strings.Join(vals, ",")
+	}
+}
+
+// bindTypeOf returns the cached bind fields of the struct type t.
+func bindTypeOf(t reflect.Type) *bindType {
+	if bt, ok := bindTypes.Load(t); ok {
+		return bt.(*bindType)
+	}
+
+	bt := &bindType{}
+	bt.err = bt.addFields(t, nil, 0)
+	actual, _ := bindTypes.LoadOrStore(t, bt)
+	return actual.(*bindType)
+}
+
+func (bt *bindType) addFields(t reflect.Type, index []int, depth int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		source, name := bindTag(sf)
+		if source < 0 {
+			// Fields of embedded structs are bound too
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct && depth < 8 {
+				if err := bt.addFields(sf.Type, fieldIndex, depth+1); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			return fmt.Errorf("Bind: field %s of %s is not exported", sf.Name, t)
+		}
+
+		f := bindField{index: fieldIndex, source: source, name: name}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			f.alloc = true
+			ft = ft.Elem()
+		}
+
+		switch {
+		case reflect.PointerTo(ft).Implements(textUnmarshalerType):
+			f.text, f.message = true, "is invalid"
+		case bindable(ft):
+			f.message = "must be " + bindKinds[ft.Kind()][0]
+		case ft.Kind() == reflect.Slice && bindable(ft.Elem()) && ft.PkgPath() == "":
+			f.message = "must be a list of " + bindKinds[ft.Elem().Kind()][1]
+		default:
+			return fmt.Errorf("Bind: unsupported type %s of field %s", sf.Type, sf.Name)
+		}
+
+		f.pointer = bindPointer(source, name)
+		bt.form = bt.form || source == bindForm
+		bt.fields = append(bt.fields, f)
+	}
+	return nil
+}
+
+// bindTag returns the source and name of the bind tag of sf, or -1 if it
+// has none.
+func bindTag(sf reflect.StructField) (int, string) {
+	for source, tag := range bindSources {
+		if name := sf.Tag.Get(tag); name != "" {
+			if name == "-" {
+				break
+			}
+			return source, name
+		}
+	}
+	return -1, ""
+}
+
+// bindPointer returns the pointer of a FieldError for the named value of
+// source, e.g. "/query/page". Header names are lower case.
+func bindPointer(source int, name string) string {
+	if source == bindHeader {
+		name = strings.ToLower(name)
+	}
+	return "/" + bindSources[source] + "/" + escapePointer(name)
+}
+
+// bindable reports whether TryParse parses into the type t.
+func bindable(t reflect.Type) bool {
+	_, ok := bindKinds[t.Kind()]
+	return ok && t.PkgPath() == ""
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type bindRequest struct {
+	bindPage
+	ID      uint64    `path:"id"`
+	Tags    []string  `query:"tag"`
+	Tenant  string    `header:"X-Tenant"`
+	Session string    `cookie:"sid"`
+	Since   time.Time `query:"since"`
+	Name    string    `json:"name"`
+	Ignored string    `query:"-"`
+}
+
+func TestCtxBind(t *testing.T) {
+	app := New()
+	app.Post("/users/:id", func(c *Ctx) (any, error) {
+		var req bindRequest
+		if err := c.Bind(&req); err != nil {
+			return nil, err
+		}
+		return req, nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/7?page=2&limit=10&tag=a&tag=b,c&since=2026-01-02T03:04:05Z&Ignored=x", strings.NewReader(`{"name":"ann"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := `{"Page":2,"Limit":10,"ID":7,"Tags":["a","b","c"],"Tenant":"acme","Session":"s1","Since":"2026-01-02T03:04:05Z","name":"ann","Ignored":""}` + "\n"
+	if rec.Body.String() != want {
+		t.Fatalf("expected %s, got %s", want, rec.Body.String())
+	}
+}
+
+func TestCtxBindInvalid(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/x?page=two&limit=-&since=yesterday", nil)
+	req.Header.Set("X-Tenant", "acme")
+	c := createCtx(nil, httptest.NewRecorder(), req, &Params{{Key: "id", Value: "x"}})
+	defer releaseCtx(c)
+
+	var dst bindRequest
+	err := c.Bind(&dst)
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+
+	want := []FieldError{
+		{Pointer: "/query/page", Message: "must be an integer"},
+		{Pointer: "/query/limit", Message: "must be an integer"},
+		{Pointer: "/path/id", Message: "must be a non-negative integer"},
+		{Pointer: "/query/since", Message: "is invalid"},
+	}
+	if got := errorFields(err); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if dst.Limit != nil || dst.Tenant != "acme" {
+		t.Fatalf("unexpected binding: %+v", dst)
+	}
+}
+
+func TestCtxBindForm(t *testing.T) {
+	form := url.Values{"name": {"ann"}, "age": {"30"}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := createCtx(nil, httptest.NewRecorder(), req, nil)
+	defer releaseCtx(c)
+
+	var dst struct {
+		Name string `form:"name"`
+		Age  uint8  `form:"age"`
+	}
+	if err := c.Bind(&dst); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if dst.Name != "ann" || dst.Age != 30 {
+		t.Fatalf("unexpected binding: %+v", dst)
+	}
+}
+
+func TestCtxBindUnsupported(t *testing.T) {
+	c := createCtx(nil, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	defer releaseCtx(c)
+
+	var dst struct {
+		Filter map[string]string `query:"filter"`
+	}
+	if err := c.Bind(&dst); err == nil || !strings.HasPrefix(err.Error(), "Bind: unsupported type") {
+		t.Fatalf("expected unsupported type error, got %v", err)
+	}
+	if err := c.Bind(dst); err == nil {
+		t.Fatal("expected an error for a non-pointer")
+	}
+}
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// paramConstraint restricts the values accepted by a path parameter, written
+// as ":name<constraint>". The constraint is either one of the built-in names
+// (int, uint, float, alpha, alnum, hex, uuid, date) or a regular expression
+// that must match the whole value.
+type paramConstraint struct {
+	pattern  string
+	match    func(string) bool
+	rejected atomic.Uint64
+}
+
+// splitConstraint splits a param wildcard like ":id<uint>" into the param
+// name "id" and the constraint "uint". The '?' of an optional param is
+// dropped.
+func splitConstraint(wildcard string) (name string, constraint string) {
+	name = strings.TrimSuffix(wildcard[1:], "?")
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		constraint = name[i+1:]
+		if j := strings.LastIndexByte(constraint, '>'); j >= 0 {
+			constraint = constraint[:j]
+		}
+		name = name[:i]
+	}
+	return name, constraint
+}
+
+// newParamConstraint compiles the constraint of a param wildcard.
+// It panics on invalid constraints, like the rest of route registration.
+func newParamConstraint(pattern string, fullPath string) *paramConstraint {
+	if pattern == "" {
+		panic("param constraints must not be empty in path '" + fullPath + "'")
+	}
+
+	pc := &paramConstraint{pattern: pattern}
+
+	switch pattern {
+	case "int":
+		pc.match = isIntParam
+	case "uint":
+		pc.match = isUintParam
+	case "float":
+		pc.match = isFloatParam
+	case "alpha":
+		pc.match = isAlphaParam
+	case "alnum":
+		pc.match = isAlnumParam
+	case "hex":
+		pc.match = isHexParam
+	case "uuid":
+		pc.match = isUUIDParam
+	case "date":
+		pc.match = isDateParam
+	default:
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			panic("invalid param constraint '" + pattern + "' in path '" + fullPath + "': " + err.Error())
+		}
+		pc.match = re.MatchString
+	}
+
+	return pc
+}
+
+func isUintParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIntParam(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUintParam(s)
+}
+
+func isFloatParam(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func isAlphaParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnumParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && ((c|0x20) < 'a' || (c|0x20) > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && ((c|0x20) < 'a' || (c|0x20) > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUIDParam accepts the canonical 8-4-4-4-12 hex form.
+func isUUIDParam(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if c := s[i]; (c < '0' || c > '9') && ((c|0x20) < 'a' || (c|0x20) > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isDateParam accepts a calendar date in the YYYY-MM-DD form.
+func isDateParam(s string) bool {
+	if len(s) != 10 || s[4] != '-' || s[7] != '-' ||
+		!isUintParam(s[:4]) || !isUintParam(s[5:7]) || !isUintParam(s[8:]) {
+		return false
+	}
+
+	year := int(s[0]-'0')*1000 + int(s[1]-'0')*100 + int(s[2]-'0')*10 + int(s[3]-'0')
+	month := int(s[5]-'0')*10 + int(s[6]-'0')
+	day := int(s[8]-'0')*10 + int(s[9]-'0')
+
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+
+	days := 31
+	switch month {
+	case 4, 6, 9, 11:
+		days = 30
+	case 2:
+		days = 28
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			days = 29
+		}
+	}
+	return day <= days
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParamConstraints(t *testing.T) {
+	app := New()
+	app.Get("/users/:id<uint>", func(c *Ctx) (any, error) { return c.Param("id"), nil })
+	app.Get("/files/:name<[a-z0-9-]+>/raw", func(c *Ctx) (any, error) { return c.Param("name"), nil })
+	app.Get("/tags/:tag<(?:go|rust)*>", func(c *Ctx) (any, error) { return c.Param("tag"), nil })
+	app.Get("/d/:date<date>", func(c *Ctx) (any, error) { return c.Param("date"), nil })
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/users/42", code: http.StatusOK, body: `"42"` + "\n"},
+		{path: "/users/abc", code: http.StatusNotFound},
+		{path: "/users/-1", code: http.StatusNotFound},
+		{path: "/files/my-file-1/raw", code: http.StatusOK, body: `"my-file-1"` + "\n"},
+		{path: "/files/My_File/raw", code: http.StatusNotFound},
+		{path: "/tags/gorustgo", code: http.StatusOK, body: `"gorustgo"` + "\n"},
+		{path: "/tags/java", code: http.StatusNotFound},
+		{path: "/d/2024-02-29", code: http.StatusOK, body: `"2024-02-29"` + "\n"},
+		{path: "/d/2023-02-29", code: http.StatusNotFound},
+		{path: "/d/2023-13-01", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.code, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Fatalf("%s: expected body %q, got %q", tt.path, tt.body, rec.Body.String())
+		}
+	}
+
+	var got []ParamConstraint
+	for _, r := range app.Routes() {
+		if r.Path == "/users/:id<uint>" {
+			if !reflect.DeepEqual(r.Params, []string{"id"}) {
+				t.Fatalf("expected params [id], got %v", r.Params)
+			}
+			got = r.Constraints
+		}
+	}
+	want := []ParamConstraint{{Param: "id", Pattern: "uint", Rejected: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constraints: got %+v want %+v", got, want)
+	}
+}
+
+func TestBuiltinParamConstraints(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{pattern: "int", value: "-12", want: true},
+		{pattern: "int", value: "1.5", want: false},
+		{pattern: "uint", value: "", want: false},
+		{pattern: "float", value: "-1.5", want: true},
+		{pattern: "float", value: ".", want: false},
+		{pattern: "alpha", value: "abcXYZ", want: true},
+		{pattern: "alpha", value: "abc1", want: false},
+		{pattern: "alnum", value: "abc1", want: true},
+		{pattern: "hex", value: "deadBEEF", want: true},
+		{pattern: "hex", value: "xyz", want: false},
+		{pattern: "uuid", value: "123e4567-e89b-12d3-a456-426614174000", want: true},
+		{pattern: "uuid", value: "123e4567e89b12d3a456426614174000", want: false},
+		{pattern: "date", value: "2000-02-29", want: true},
+		{pattern: "date", value: "1900-02-29", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := newParamConstraint(tt.pattern, "/").match(tt.value); got != tt.want {
+			t.Fatalf("%s(%q): expected %v, got %v", tt.pattern, tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestInvalidParamConstraintPanics(t *testing.T) {
+	for _, path := range []string{"/a/:id<[a-z>", "/a/:id<>", "/a/*rest<int>"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", path)
+				}
+			}()
+			New().Get(path, func(c *Ctx) (any, error) { return nil, nil })
+		}()
+	}
+}
@@ -60,12 +60,16 @@ type Ctx struct {
 	w                      http.ResponseWriter
 	r                      *http.Request
 	param                  *Params
+	route                  *Route
+	routeMethod            string
+	version                string
 	query                  url.Values
 	userId                 uint64
 	formDataState          uint8
 	statusCode             int
 	responseCommitted      bool
 	acceptType             mediaType
+	acceptWriter           *mediaWriter
 	acceptTypeCached       bool
 	contentTypeValue       string
 	contentTypeValueCached bool
@@ -182,6 +186,23 @@ func (c *Ctx) Path() string {
 	return c.r.URL.Path
 }
 
+// Route describes the route serving the request, or is the zero
+// MatchedRoute if no route matched, e.g. in Pre middleware for a 404.
+func (c *Ctx) Route() MatchedRoute {
+	r := c.route
+	if r == nil {
+		return MatchedRoute{}
+	}
+	return MatchedRoute{
+		Method:  c.routeMethod,
+		Host:    r.host,
+		Version: r.version,
+		Pattern: r.path,
+		Name:    r.name,
+		Meta:    r.meta,
+	}
+}
+
 // Body returns the request body.
 func (c *Ctx) Body() io.ReadCloser {
 	return c.r.Body
@@ -252,12 +273,28 @@ func (c *Ctx) IsForm() bool {
 }
 
 // TryParseBody attempts to parse the request body based on its Content-Type and decode it into the provided value.
+// Structs are then checked with Validate if Application.AutoValidate is set.
 func (c *Ctx) TryParseBody(val any) error {
+	if err := c.parseBody(val); err != nil {
+		return err
+	}
+	return c.autoValidate(val)
+}
+
+// parseBody decodes the request body into val.
+func (c *Ctx) parseBody(val any) error {
 
 	if c.r == nil || c.r.Body == nil {
 		return io.EOF
 	}
 
+	if c.app != nil && c.app.mediaReaders != nil {
+		typ, _ := splitMediaType(c.requestContentType())
+		if reader := c.app.mediaReaders[strings.ToLower(typ)]; reader != nil {
+			return reader(c, val)
+		}
+	}
+
 	switch c.requestMediaType() {
 	case mediaJSON:
 		if c.app != nil && c.app.hasReaders {
@@ -291,6 +328,13 @@ func (c *Ctx) TryParseBody(val any) error {
 		}
 		dec := xml.NewDecoder(c.r.Body)
 		return dec.Decode(val)
+	case mediaMsgPack:
+		if c.app != nil && c.app.hasReaders {
+			if reader := c.app.readers[mediaMsgPack]; reader != nil {
+				return reader(c, val)
+			}
+		}
+		return c.readMsgPack(val)
 	default:
 		return ErrContentType
 	}
@@ -667,6 +711,14 @@ func (c *Ctx) SetConnection(val string) {
 	c.SetHeader("Connection", val)
 }
 
+// Version returns the API version the request asked for, by path prefix,
+// Api-Version header or the version parameter of Accept, or else the
+// DefaultVersion of the application. It is empty if that version has no
+// routes.
+func (c *Ctx) Version() string {
+	return c.version
+}
+
 // SetVersion set `version` header
 func (c *Ctx) SetVersion(version string) {
 	c.SetHeader("Version", version)
@@ -734,6 +786,15 @@ func (c *Ctx) writeMedia(mt mediaType, val any) error {
 			}
 		}
 		return c.writeXML(val)
+	case mediaMsgPack:
+		if c.app != nil && c.app.hasWriters {
+			if writer := c.app.writers[mediaMsgPack]; writer != nil {
+				return writer(c, val)
+			}
+		}
+		return c.writeMsgPack(val)
+	case mediaCustom:
+		return c.acceptWriter.write(c, val)
 	default:
 		if c.app != nil && c.app.hasWriters {
 			if writer := c.app.writers[mediaJSON]; writer != nil {
@@ -744,16 +805,40 @@ func (c *Ctx) writeMedia(mt mediaType, val any) error {
 	}
 }
 
+// responseMediaType returns the media type negotiated with the Accept
+// header, or JSON if the request accepts none.
 func (c *Ctx) responseMediaType() mediaType {
-	if c.acceptTypeCached {
-		return c.acceptType
+	if !c.acceptTypeCached {
+		if c.app != nil && c.app.mediaWriters != nil {
+			c.acceptType, c.acceptWriter = c.app.negotiate(c.Accept())
+		} else {
+			c.acceptType = acceptMediaType(c.Accept())
+		}
+		c.acceptTypeCached = true
+	}
+	if c.acceptType == mediaUnknown {
+		return mediaJSON
 	}
-
-	c.acceptType = acceptMediaType(c.Accept())
-	c.acceptTypeCached = true
 	return c.acceptType
 }
 
+// writeCodeByMedia writes the status line of a response with a body in the
+// media type mt.
+func (c *Ctx) writeCodeByMedia(mt mediaType, code int) {
+	if mt == mediaCustom {
+		writeCodeByContentType(c.w, c.acceptWriter.contentType, code)
+		return
+	}
+	writeCodeByMedia(c.w, mt, code)
+}
+
+// acceptable reports whether the request accepts a media type responses
+// are written in.
+func (c *Ctx) acceptable() bool {
+	c.responseMediaType()
+	return c.acceptType != mediaUnknown
+}
+
 func (c *Ctx) requestMediaType() mediaType {
 	if c.contentTypeCached {
 		return c.contentType
@@ -45,6 +45,11 @@ var (
 	// This error is returned when request processing exceeds a configured deadline.
 	ErrRequestTimeout = NewErr(http.StatusRequestTimeout, "REQUESTTIMEOUT")
 
+	// ErrRequestEntityTooLarge represents an HTTP 413 Request Entity Too Large error.
+	// This error is returned when a request body exceeds the size a handler or middleware reads.
+	// Example: a JSON body over the limit of OpenAPIValidatorWithOptions.
+	ErrRequestEntityTooLarge = NewErr(http.StatusRequestEntityTooLarge, "REQUESTENTITYTOOLARGE")
+
 	// ErrUnauthorized represents an HTTP 401 Unauthorized error.
 	// This error indicates that the request lacks valid authentication credentials (e.g., token, username/password).
 	// Return this when a user attempts to access a protected resource without proper authorization.
@@ -72,6 +77,12 @@ var (
 	// Tip: Responses typically include an Allow header listing permitted methods.
 	ErrMethodNotAllowed = NewErr(http.StatusMethodNotAllowed, "METHODNOTALLOWED")
 
+	// ErrNotAcceptable represents an HTTP 406 Not Acceptable error.
+	// This error is returned when the Accept header of the request rules out every media type
+	// the server can write the response in.
+	// Example: a client accepting only text/html from a JSON API.
+	ErrNotAcceptable = NewErr(http.StatusNotAcceptable, "NOTACCEPTABLE")
+
 	// ErrNotImplemented represents an HTTP 501 Not Implemented error.
 	// This error indicates that the server does not support the functionality required to fulfill the request.
 	// Often used for unimplemented features or unsupported HTTP methods in development.
@@ -112,6 +123,12 @@ var (
 	// Example: an invalid date string in a form submission.
 	ErrInvalid = NewErr(http.StatusBadRequest, "INVALID")
 
+	// ErrUnprocessableEntity represents an HTTP 422 Unprocessable Entity error.
+	// This error is returned when the request is well-formed but its content fails validation.
+	// Validate reports it as a *ValidationError listing the offending fields.
+	// Example: a sign-up form with a malformed email address.
+	ErrUnprocessableEntity = NewErr(http.StatusUnprocessableEntity, "UNPROCESSABLEENTITY")
+
 	// ErrServerNotInitialized is returned when attempting to perform operations (e.g., Shutdown)
 	// on an Application instance whose HTTP server (app.srv) has not been initialized.
 	// This typically occurs if the serve method has not been called or if the server was explicitly reset.
@@ -170,3 +187,67 @@ func errCode(err error) int {
 
 	return http.StatusBadRequest
 }
+
+// FieldError describes a request field that failed validation. Pointer is a
+// JSON pointer into the request, its first token naming the part: e.g.
+// "/path/id", "/query/page", "/header/x-token" or "/body/items/0/name".
+type FieldError struct {
+	Pointer string `json:"pointer" xml:"pointer"`
+	Message string `json:"message" xml:"message"`
+}
+
+// fieldsError is err along with the fields that failed validation.
+type fieldsError struct {
+	err    error
+	Fields []FieldError
+}
+
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldsError) Code() int {
+	return errCode(e.err)
+}
+
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+func (e *fieldsError) fieldErrors() []FieldError {
+	return e.Fields
+}
+
+// InvalidError is ErrInvalid along with the fields that failed validation,
+// so errors.Is(err, ErrInvalid) holds. The default error writer and
+// JSONErrorHandler report the fields.
+type InvalidError struct {
+	fieldsError
+}
+
+// NewInvalidError returns ErrInvalid with the invalid fields.
+func NewInvalidError(fields []FieldError) *InvalidError {
+	return &InvalidError{fieldsError{err: ErrInvalid, Fields: fields}}
+}
+
+// ValidationError is ErrUnprocessableEntity along with the fields that
+// failed the rules checked by Validate, so errors.Is(err,
+// ErrUnprocessableEntity) holds. The default error writer and
+// JSONErrorHandler report the fields.
+type ValidationError struct {
+	fieldsError
+}
+
+// NewValidationError returns ErrUnprocessableEntity with the failed fields.
+func NewValidationError(fields []FieldError) *ValidationError {
+	return &ValidationError{fieldsError{err: ErrUnprocessableEntity, Fields: fields}}
+}
+
+// errorFields returns the invalid fields carried by err, if any.
+func errorFields(err error) []FieldError {
+	var fe interface{ fieldErrors() []FieldError }
+	if errors.As(err, &fe) {
+		return fe.fieldErrors()
+	}
+	return nil
+}
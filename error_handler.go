@@ -2,9 +2,10 @@ package web
 
 // ErrorBody is a structured API error payload for opt-in error handlers.
 type ErrorBody struct {
-	Code      int    `json:"code" xml:"code"`
-	Message   string `json:"message" xml:"message"`
-	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty"`
+	Code      int          `json:"code" xml:"code"`
+	Message   string       `json:"message" xml:"message"`
+	RequestID string       `json:"request_id,omitempty" xml:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty" xml:"errors>error,omitempty"`
 }
 
 // JSONErrorHandler returns an ErrorHandler that writes a structured JSON error body.
@@ -14,6 +15,7 @@ func JSONErrorHandler(includeRequestID bool) ErrorHandler {
 		body := ErrorBody{
 			Code:    errCode(err),
 			Message: err.Error(),
+			Errors:  errorFields(err),
 		}
 		if includeRequestID {
 			body.RequestID = c.RequestID()
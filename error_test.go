@@ -1,8 +1,10 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -23,3 +25,30 @@ func TestErrCodeSupportsWrappedErrFn(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, code)
 	}
 }
+
+func TestFieldsErrors(t *testing.T) {
+	t.Parallel()
+
+	fields := []FieldError{{Pointer: "/query/page", Message: "is invalid"}}
+	tests := []struct {
+		err  error
+		base error
+		code int
+	}{
+		{err: NewInvalidError(fields), base: ErrInvalid, code: http.StatusBadRequest},
+		{err: NewValidationError(fields), base: ErrUnprocessableEntity, code: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", tt.err)
+		if !errors.Is(err, tt.base) || tt.err.Error() != tt.base.Error() {
+			t.Fatalf("expected %v, got %v", tt.base, tt.err)
+		}
+		if code := errCode(err); code != tt.code {
+			t.Fatalf("expected status %d, got %d", tt.code, code)
+		}
+		if got := errorFields(err); !reflect.DeepEqual(got, fields) {
+			t.Fatalf("expected fields %v, got %v", fields, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"sort"
+	"strings"
+)
+
+// fallback answers the requests below a group prefix that no route matches.
+type fallback struct {
+	prefix  string
+	group   *RouteGroup
+	handler Next
+	next    Next
+}
+
+// NotFound sets the callback for requests below the group prefix that match
+// no route. The group with the longest matching prefix wins over the
+// Application.NotFound handler. The callback runs through the application and
+// group middleware, and its errors through the error handler, so returning
+// ErrNotFound renders a 404 like any route error.
+func (g *RouteGroup) NotFound(next Next) {
+	g.setFallback(next, false)
+}
+
+// MethodNotAllowed sets the callback for requests below the group prefix
+// whose path only matches routes of other methods, like NotFound. The Allow
+// header is set before it runs.
+func (g *RouteGroup) MethodNotAllowed(next Next) {
+	g.setFallback(next, true)
+}
+
+func (g *RouteGroup) setFallback(next Next, methodNotAllowed bool) {
+	if next == nil {
+		panic("callback must not be nil")
+	}
+
+	app := g.app
+	app.update(func(t *routeTable) {
+		rt := t.scope(g.host, g.version)
+		if rt == nil {
+			return
+		}
+		fb := &fallback{
+			prefix:  g.prefix,
+			group:   g,
+			handler: next,
+			next:    wrapNext(next, app.chainFor(g, nil)),
+		}
+		if methodNotAllowed {
+			rt.methodNotAllowed = withFallback(rt.methodNotAllowed, fb)
+		} else {
+			rt.notFound = withFallback(rt.notFound, fb)
+		}
+	})
+}
+
+// withFallback returns a copy of list with fb added, replacing the fallback
+// for the same prefix, ordered by descending prefix length.
+func withFallback(list []*fallback, fb *fallback) []*fallback {
+	out := make([]*fallback, 0, len(list)+1)
+	for _, f := range list {
+		if f.prefix != fb.prefix {
+			out = append(out, f)
+		}
+	}
+	out = append(out, fb)
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].prefix) > len(out[j].prefix)
+	})
+	return out
+}
+
+// findFallback returns the fallback with the longest prefix of path.
+func findFallback(list []*fallback, path string) *fallback {
+	for _, f := range list {
+		if hasPathPrefix(path, f.prefix) {
+			return f
+		}
+	}
+	return nil
+}
+
+// hasPathPrefix reports whether path is prefix or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || prefix == "" ||
+		prefix[len(prefix)-1] == '/' || path[len(prefix)] == '/'
+}
+
+// rebuildFallbacks composes the fallbacks of list again, like Build does
+// for routes.
+func (app *Application) rebuildFallbacks(list []*fallback) []*fallback {
+	if len(list) == 0 {
+		return list
+	}
+	out := make([]*fallback, len(list))
+	for i, f := range list {
+		c := *f
+		c.next = wrapNext(c.handler, app.chainFor(c.group, nil))
+		out[i] = &c
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroupFallbacks(t *testing.T) {
+	app := New()
+	app.Group("").NotFound(func(c *Ctx) (any, error) {
+		c.SetContentType("text/html")
+		_, err := c.Write([]byte("index"))
+		return nil, err
+	})
+
+	api := app.Group("/api", func(next Next) Next {
+		return func(c *Ctx) (any, error) {
+			c.SetHeader("X-Group", "api")
+			return next(c)
+		}
+	})
+	api.Get("/users", func(c *Ctx) (any, error) { return nil, nil })
+	api.NotFound(func(c *Ctx) (any, error) { return nil, ErrNotFound })
+	api.MethodNotAllowed(func(c *Ctx) (any, error) {
+		c.SetHeader("X-Allow", c.ResponseWriter().Header().Get("Allow"))
+		return nil, ErrMethodNotAllowed
+	})
+	api.Group("/admin").NotFound(func(c *Ctx) (any, error) { return nil, ErrForbidden })
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		group  string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/dashboard/settings", code: http.StatusOK, body: "index"},
+		{method: http.MethodGet, path: "/apiary", code: http.StatusOK, body: "index"},
+		{method: http.MethodGet, path: "/api/missing", code: http.StatusNotFound, group: "api", body: "NOTFOUND"},
+		{method: http.MethodGet, path: "/api", code: http.StatusNotFound, group: "api", body: "NOTFOUND"},
+		{method: http.MethodGet, path: "/api/admin/x", code: http.StatusForbidden, group: "api"},
+		{method: http.MethodPost, path: "/api/users", code: http.StatusMethodNotAllowed, group: "api"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Accept", "application/json")
+		app.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s: expected %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+		}
+		if got := rec.Header().Get("X-Group"); got != tt.group {
+			t.Fatalf("%s %s: expected group %q, got %q", tt.method, tt.path, tt.group, got)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Fatalf("%s %s: expected body with %q, got %q", tt.method, tt.path, tt.body, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil))
+	if got := rec.Header().Get("X-Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected Allow to be set before the callback, got %q", got)
+	}
+}
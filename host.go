@@ -0,0 +1,130 @@
+package web
+
+import "strings"
+
+// hostRouter is a routing scope selected by the request host.
+type hostRouter struct {
+	router
+	labels []string
+}
+
+// Host returns a route group whose routes only match requests for the given
+// host. Labels written as ":name" match any single label and are exposed as
+// params, e.g. app.Host(":tenant.example.com") makes c.Param("tenant") return
+// "acme" for acme.example.com. Exact hosts are preferred over patterns, which
+// are tried in registration order. Requests for hosts without a match are
+// routed by the application's own routes.
+func (app *Application) Host(pattern string, middleware ...Middleware) *RouteGroup {
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	if pattern == "" {
+		panic("host pattern must not be empty")
+	}
+
+	h := &hostRouter{labels: strings.Split(pattern, ".")}
+	h.host = pattern
+	for _, label := range h.labels {
+		switch {
+		case label == "":
+			panic("host labels must not be empty in host '" + pattern + "'")
+		case label == ":":
+			panic("host params must be named with a non-empty name in host '" + pattern + "'")
+		case label[0] == ':':
+			h.hostParams++
+		case strings.IndexByte(label, ':') >= 0:
+			panic("host params must span a whole label and ports are not allowed in host '" + pattern + "'")
+		}
+	}
+
+	app.update(func(t *routeTable) {
+		if t.host(pattern) != nil {
+			return
+		}
+		if h.hostParams == 0 {
+			if t.hosts == nil {
+				t.hosts = make(map[string]*hostRouter)
+			}
+			t.hosts[pattern] = h
+		} else {
+			t.wildcardHosts = append(t.wildcardHosts, h)
+		}
+	})
+
+	return &RouteGroup{
+		app:        app,
+		host:       pattern,
+		middleware: append(Chain(nil), middleware...),
+	}
+}
+
+// routerForHost returns the routing scope for the request host, and the
+// matched host if it is not the application itself.
+func (t *routeTable) routerForHost(host string) (*router, *hostRouter) {
+	if t.hosts == nil && t.wildcardHosts == nil {
+		return &t.router, nil
+	}
+
+	name := hostname(host)
+
+	if h := t.hosts[name]; h != nil {
+		return &h.router, h
+	}
+
+	for _, h := range t.wildcardHosts {
+		if h.match(name, nil) {
+			return &h.router, h
+		}
+	}
+
+	return &t.router, nil
+}
+
+// captureParams appends the host params to ps.
+func (h *hostRouter) captureParams(host string, ps *Params, app *Application) *Params {
+	if ps == nil {
+		ps = app.params.get()
+	}
+	h.match(hostname(host), ps)
+	return ps
+}
+
+// match reports whether name matches the host pattern and, if ps is not nil,
+// appends the values of the host params to it.
+func (h *hostRouter) match(name string, ps *Params) bool {
+	last := len(h.labels) - 1
+	for i, label := range h.labels {
+		end := strings.IndexByte(name, '.')
+		if i == last {
+			if end >= 0 {
+				return false
+			}
+			end = len(name)
+		} else if end < 0 {
+			return false
+		}
+
+		part := name[:end]
+		if label[0] == ':' {
+			if part == "" {
+				return false
+			}
+			if ps != nil {
+				*ps = append(*ps, Param{Key: label[1:], Value: part})
+			}
+		} else if part != label {
+			return false
+		}
+
+		if end < len(name) {
+			name = name[end+1:]
+		}
+	}
+	return true
+}
+
+// hostname returns host without port and trailing dot, in lower case.
+func hostname(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	app := New()
+	app.Get("/", func(c *Ctx) (any, error) { return "default", nil })
+
+	api := app.Host("api.example.com")
+	api.Get("/", func(c *Ctx) (any, error) { return "api", nil })
+
+	tenant := app.Host(":tenant.example.com")
+	tenant.Get("/users/:id", func(c *Ctx) (any, error) {
+		return c.Param("tenant") + "/" + c.Param("id"), nil
+	})
+
+	tests := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{host: "api.example.com", path: "/", code: http.StatusOK, body: `"api"` + "\n"},
+		{host: "API.example.com:8080", path: "/", code: http.StatusOK, body: `"api"` + "\n"},
+		{host: "acme.example.com", path: "/users/7", code: http.StatusOK, body: `"acme/7"` + "\n"},
+		{host: "acme.example.com", path: "/", code: http.StatusNotFound},
+		{host: "a.b.example.com", path: "/users/7", code: http.StatusNotFound},
+		{host: "other.test", path: "/", code: http.StatusOK, body: `"default"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s%s: expected status %d, got %d", tt.host, tt.path, tt.code, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Fatalf("%s%s: expected body %q, got %q", tt.host, tt.path, tt.body, rec.Body.String())
+		}
+	}
+
+	routes := app.Routes()
+	if len(routes) != 3 || routes[1].Host != ":tenant.example.com" || routes[2].Host != "api.example.com" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+}
+
+func TestHostInvalidPatternPanics(t *testing.T) {
+	for _, pattern := range []string{"", "api.example.com:8080", "a..b", ":.example.com", "x:y.example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%q: expected panic", pattern)
+				}
+			}()
+			New().Host(pattern)
+		}()
+	}
+}
@@ -1,6 +1,10 @@
 package web
 
-import "strings"
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
 
 type mediaType uint8
 
@@ -11,18 +15,252 @@ const (
 	mediaOctetStream
 	mediaAvro
 	mediaXML
+	mediaMsgPack
+
+	// mediaCustom is a media type registered with RegisterWriter other than
+	// the built-in ones, which have a slot each.
+	mediaCustom
 )
 
-const mediaTypeSlots = int(mediaXML) + 1
+const mediaTypeSlots = int(mediaMsgPack) + 1
+
+// mediaWriter is a writer registered for a custom media type.
+type mediaWriter struct {
+	contentType  string // lower case, without parameters
+	typ, subtype string
+	write        Writer
+}
+
+// mediaOffers are the media types responses are written in, most preferred
+// first. Every one has a built-in writer, which RegisterWriter may replace.
+var mediaOffers = [...]struct {
+	typ, subtype string
+	mt           mediaType
+}{
+	{"application", "json", mediaJSON},
+	{"application", "xml", mediaXML},
+	{"text", "xml", mediaXML},
+	{"application", "x-gob", mediaGOB},
+	{"application", "octet-stream", mediaOctetStream},
+	{"application", "x-avro", mediaAvro},
+	{"application", "x-msgpack", mediaMsgPack},
+	{"application", "msgpack", mediaMsgPack},
+	{"application", "vnd.msgpack", mediaMsgPack},
+}
+
+// acceptCache holds the negotiated media types of Accept headers.
+type acceptCache struct {
+	m    sync.Map // Accept header -> acceptResult
+	size atomic.Int32
+}
+
+type acceptResult struct {
+	mt     mediaType
+	writer *mediaWriter
+}
+
+// acceptCacheMax bounds the number of Accept headers a cache holds, and
+// acceptCacheKeyMax the length of the headers it holds. Longer headers are
+// negotiated every time, so that junk headers neither pin memory nor crowd
+// out the common ones.
+const (
+	acceptCacheMax    = 512
+	acceptCacheKeyMax = 256
+)
+
+func (ac *acceptCache) load(header string) (acceptResult, bool) {
+	v, ok := ac.m.Load(header)
+	if !ok {
+		return acceptResult{}, false
+	}
+	return v.(acceptResult), true
+}
+
+func (ac *acceptCache) store(header string, res acceptResult) {
+	if len(header) <= acceptCacheKeyMax && ac.size.Load() < acceptCacheMax {
+		if _, loaded := ac.m.LoadOrStore(header, res); !loaded {
+			ac.size.Add(1)
+		}
+	}
+}
 
+// builtinAccepts caches the negotiation among the built-in media types.
+var builtinAccepts acceptCache
+
+// acceptMediaType negotiates the media type of a response with the Accept
+// header as in RFC 9110: every offer takes the q-value of the most specific
+// media range matching it, e.g. "application/*" over "*/*", and the offer
+// with the highest q-value wins, earlier offers breaking ties. An empty
+// header accepts JSON, and mediaUnknown means no offer is acceptable.
 func acceptMediaType(header string) mediaType {
-	mt := parseMediaType(header)
-	if mt == mediaUnknown {
+	switch header {
+	case "", "*/*", "application/json":
 		return mediaJSON
+	case "application/xml", "text/xml":
+		return mediaXML
+	case "application/x-msgpack":
+		return mediaMsgPack
+	}
+
+	if res, ok := builtinAccepts.load(header); ok {
+		return res.mt
+	}
+	res := negotiateMediaType(header, nil)
+	builtinAccepts.store(header, res)
+	return res.mt
+}
+
+// negotiate negotiates the media type of a response with the Accept header
+// among the built-in media types and the registered custom writers.
+func (app *Application) negotiate(header string) (mediaType, *mediaWriter) {
+	if header == "" {
+		return mediaJSON, nil
+	}
+	if res, ok := app.accepts.load(header); ok {
+		return res.mt, res.writer
+	}
+	res := negotiateMediaType(header, app.mediaWriters)
+	app.accepts.store(header, res)
+	return res.mt, res.writer
+}
+
+// negotiateMediaType negotiates among the built-in media types and the
+// custom writers, which come last in preference. Ranges with the structured
+// suffix +json, e.g. "application/vnd.acme+json", match JSON unless a custom
+// writer is registered for them. Ranges with the suffix +xml do not match
+// XML: browsers accept application/xhtml+xml or image/svg+xml for pages and
+// images, not for any XML.
+func negotiateMediaType(header string, custom []*mediaWriter) acceptResult {
+	var (
+		quality     [len(mediaOffers)]int // in thousandths
+		specificity [len(mediaOffers)]int // of the range setting quality
+
+		customQuality     []int
+		customSpecificity []int
+	)
+	if len(custom) > 0 {
+		customQuality = make([]int, len(custom))
+		customSpecificity = make([]int, len(custom))
+	}
+
+	for header != "" {
+		var r string
+		r, header, _ = strings.Cut(header, ",")
+		typ, params := splitMediaType(r)
+		if typ == "" {
+			continue
+		}
+		q, ok := parseQuality(params)
+		if !ok {
+			continue
+		}
+		main, sub, ok := strings.Cut(typ, "/")
+		if !ok {
+			continue
+		}
+
+		matched := false
+		for i, offer := range custom {
+			if spec := mediaRangeMatch(main, sub, offer.typ, offer.subtype); spec > customSpecificity[i] {
+				customSpecificity[i], customQuality[i] = spec, q
+				matched = matched || spec == 3
+			}
+		}
+		if !matched {
+			if m, s := structuredSuffix(main, sub); s == "json" {
+				main, sub = m, s
+			}
+		}
+		for i, offer := range mediaOffers {
+			if spec := mediaRangeMatch(main, sub, offer.typ, offer.subtype); spec > specificity[i] {
+				specificity[i], quality[i] = spec, q
+			}
+		}
+	}
+
+	best, res := 0, acceptResult{}
+	for i, q := range quality {
+		if q > best {
+			best, res = q, acceptResult{mt: mediaOffers[i].mt}
+		}
+	}
+	for i, q := range customQuality {
+		if q > best {
+			best, res = q, acceptResult{mt: mediaCustom, writer: custom[i]}
+		}
+	}
+	return res
+}
+
+// mediaRangeMatch returns how specifically the media range main/sub matches
+// the media type typ/subtype: 3 for the type itself, 2 for typ/*, 1 for */*
+// and 0 for no match.
+func mediaRangeMatch(main, sub, typ, subtype string) int {
+	switch {
+	case main == "*" && sub == "*":
+		return 1
+	case !strings.EqualFold(main, typ):
+		return 0
+	case sub == "*":
+		return 2
+	case strings.EqualFold(sub, subtype):
+		return 3
+	default:
+		return 0
 	}
-	return mt
 }
 
+// structuredSuffix maps a media type with the structured suffix +json or
+// +xml to application/json or application/xml.
+func structuredSuffix(main, sub string) (string, string) {
+	i := strings.LastIndexByte(sub, '+')
+	if i < 0 {
+		return main, sub
+	}
+	switch suffix := sub[i+1:]; {
+	case strings.EqualFold(suffix, "json"):
+		return "application", "json"
+	case strings.EqualFold(suffix, "xml"):
+		return "application", "xml"
+	}
+	return main, sub
+}
+
+// parseQuality returns the q parameter of the media range parameters params
+// in thousandths, 1000 if it has none, and false if it is malformed.
+func parseQuality(params string) (int, bool) {
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+		k, v, ok := strings.Cut(param, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "q") {
+			continue
+		}
+
+		v = strings.TrimSpace(v)
+		if v == "" || (v[0] != '0' && v[0] != '1') || len(v) > 5 || (len(v) > 1 && v[1] != '.') {
+			return 0, false
+		}
+		q, scale := int(v[0]-'0')*1000, 100
+		for i := 2; i < len(v); i++ {
+			if v[i] < '0' || v[i] > '9' {
+				return 0, false
+			}
+			q += int(v[i]-'0') * scale
+			scale /= 10
+		}
+		if q > 1000 {
+			return 0, false
+		}
+		return q, true
+	}
+	return 1000, true
+}
+
+// parseMediaType returns the built-in media type of the first media range
+// of a Content-Type or Accept value, falling back to JSON and XML for the
+// structured suffixes +json and +xml. Parameters are ignored; read them with
+// mediaTypeParam.
 func parseMediaType(header string) mediaType {
 	if header == "" {
 		return mediaUnknown
@@ -41,22 +279,77 @@ func parseMediaType(header string) mediaType {
 		return mediaXML
 	}
 
-	// Fast prefix path for values with parameters or media-ranges, e.g.
-	// "application/json; charset=utf-8" or "application/json, */*".
-	switch {
-	case strings.HasPrefix(header, "application/json"):
-		return mediaJSON
-	case strings.HasPrefix(header, "application/x-gob"):
-		return mediaGOB
-	case strings.HasPrefix(header, "application/octet-stream"):
-		return mediaOctetStream
-	case strings.HasPrefix(header, "application/x-avro"):
-		return mediaAvro
-	case strings.HasPrefix(header, "application/xml"), strings.HasPrefix(header, "text/xml"):
-		return mediaXML
-	default:
-		return mediaUnknown
+	// Values with parameters or several media ranges, e.g.
+	// "application/json; charset=utf-8" or "application/json, */*"
+	typ, _ := splitMediaType(header)
+	if mt := builtinMediaType(typ); mt != mediaUnknown {
+		return mt
+	}
+
+	// Structured syntax suffixes, e.g. "application/vnd.acme+json"
+	if main, sub, ok := strings.Cut(typ, "/"); ok && strings.IndexByte(sub, '+') >= 0 {
+		switch _, sub = structuredSuffix(main, sub); sub {
+		case "json":
+			return mediaJSON
+		case "xml":
+			return mediaXML
+		}
+	}
+	return mediaUnknown
+}
+
+var mediaTypeNames = [...]struct {
+	name string
+	mt   mediaType
+}{
+	{"application/json", mediaJSON},
+	{"*/*", mediaJSON},
+	{"application/x-gob", mediaGOB},
+	{"application/octet-stream", mediaOctetStream},
+	{"application/x-avro", mediaAvro},
+	{"application/xml", mediaXML},
+	{"text/xml", mediaXML},
+	{"application/x-msgpack", mediaMsgPack},
+	{"application/msgpack", mediaMsgPack},
+	{"application/vnd.msgpack", mediaMsgPack},
+}
+
+// builtinMediaType returns the built-in media type named typ, without
+// parameters.
+func builtinMediaType(typ string) mediaType {
+	for _, m := range mediaTypeNames {
+		if strings.EqualFold(typ, m.name) {
+			return m.mt
+		}
+	}
+	return mediaUnknown
+}
+
+// splitMediaType splits the first media range of header into its type and
+// its parameters, e.g. "application/json; version=2, */*" into
+// "application/json" and "version=2".
+func splitMediaType(header string) (typ, params string) {
+	if i := strings.IndexByte(header, ','); i >= 0 {
+		header = header[:i]
+	}
+	typ, params, _ = strings.Cut(header, ";")
+	return strings.TrimSpace(typ), strings.TrimSpace(params)
+}
+
+// mediaTypeParam returns the value of the parameter key of the first media
+// range of header, e.g. "2" for the key "version" and the header
+// "application/json; version=2".
+func mediaTypeParam(header, key string) string {
+	_, params := splitMediaType(header)
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+		k, v, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.Trim(strings.TrimSpace(v), `"`)
+		}
 	}
+	return ""
 }
 
 func contentTypeForMedia(mt mediaType) string {
@@ -69,6 +362,8 @@ func contentTypeForMedia(mt mediaType) string {
 		return "application/x-avro"
 	case mediaXML:
 		return "application/xml"
+	case mediaMsgPack:
+		return "application/x-msgpack"
 	default:
 		return "application/json"
 	}
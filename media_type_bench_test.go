@@ -31,3 +31,14 @@ func BenchmarkAcceptMediaTypeEmpty(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkAcceptMediaTypeQualityValues(b *testing.B) {
+	const header = "text/html;q=0.9, application/xml, application/json;q=0.5"
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if got := acceptMediaType(header); got != mediaXML {
+			b.Fatalf("unexpected media type: %v", got)
+		}
+	}
+}
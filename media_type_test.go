@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -123,3 +124,209 @@ func TestApplicationCustomReader(t *testing.T) {
 		t.Fatalf("expected custom reader to set payload, got %q", got)
 	}
 }
+
+func TestParseMediaTypeParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header  string
+		mt      mediaType
+		version string
+	}{
+		{header: "application/json", mt: mediaJSON},
+		{header: "Application/JSON; Version=2", mt: mediaJSON, version: "2"},
+		{header: "application/xml;charset=utf-8; version=\"3\"", mt: mediaXML, version: "3"},
+		{header: "application/json, application/xml; version=2", mt: mediaJSON},
+		{header: "application/jsonx", mt: mediaUnknown},
+		{header: "text/html, application/json", mt: mediaUnknown},
+		{header: "application/problem+xml; version=1", mt: mediaXML, version: "1"},
+		{header: "application/vnd.acme.v2+JSON", mt: mediaJSON},
+		{header: "text/json", mt: mediaUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := parseMediaType(tt.header); got != tt.mt {
+			t.Fatalf("%q: expected media type %v, got %v", tt.header, tt.mt, got)
+		}
+		if got := mediaTypeParam(tt.header, "version"); got != tt.version {
+			t.Fatalf("%q: expected version %q, got %q", tt.header, tt.version, got)
+		}
+	}
+}
+
+func TestAcceptMediaTypeNegotiation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		mt     mediaType
+	}{
+		{header: "", mt: mediaJSON},
+		{header: "text/html;q=0.9, application/xml", mt: mediaXML},
+		{header: "application/json;q=0, */*", mt: mediaXML},
+		{header: "application/json;q=0.5, application/*;q=0.8", mt: mediaXML},
+		{header: "application/*;q=0.8, application/json", mt: mediaJSON},
+		{header: "application/x-gob;q=0.3, */*;q=0.2", mt: mediaGOB},
+		{header: "Application/X-Avro", mt: mediaAvro},
+		{header: "text/*", mt: mediaXML},
+		{header: "text/html", mt: mediaUnknown},
+		{header: "application/json;q=0", mt: mediaUnknown},
+		{header: "application/json;q=2, application/xml;q=0.1", mt: mediaXML},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ { // negotiated, then cached
+			if got := acceptMediaType(tt.header); got != tt.mt {
+				t.Fatalf("%q: expected %v, got %v", tt.header, tt.mt, got)
+			}
+		}
+	}
+}
+
+func TestAcceptBrowserHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		mt     mediaType
+	}{
+		// Navigations prefer application/xml to */*
+		{header: "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7", mt: mediaXML},
+		{header: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", mt: mediaXML},
+		{header: "text/html,application/xhtml+xml,*/*;q=0.8", mt: mediaJSON},
+		// Images and fetches fall back to */*
+		{header: "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8", mt: mediaJSON},
+		{header: "application/json, text/plain, */*", mt: mediaJSON},
+		{header: "*/*", mt: mediaJSON},
+		{header: "application/xhtml+xml", mt: mediaUnknown},
+		{header: "application/problem+json", mt: mediaJSON},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateMediaType(tt.header, nil).mt; got != tt.mt {
+			t.Fatalf("%q: expected %v, got %v", tt.header, tt.mt, got)
+		}
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Get("/users", func(c *Ctx) (any, error) { return []string{"ann"}, nil })
+
+	tests := []struct {
+		accept string
+		code   int
+		ctype  string
+	}{
+		{accept: "text/html;q=0.9, application/xml", code: http.StatusOK, ctype: "application/xml"},
+		{accept: "text/html", code: http.StatusNotAcceptable, ctype: "application/json"},
+		{accept: "application/json;q=0", code: http.StatusNotAcceptable, ctype: "application/json"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Accept", tt.accept)
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%q: expected status %d, got %d", tt.accept, tt.code, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.ctype {
+			t.Fatalf("%q: expected Content-Type %q, got %q", tt.accept, tt.ctype, got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept" {
+			t.Fatalf("%q: expected Vary: Accept, got %q", tt.accept, got)
+		}
+	}
+}
+
+func TestApplicationCustomMediaTypes(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	if err := app.RegisterWriter("text/csv; charset=utf-8", func(c *Ctx, v any) error {
+		_, err := c.Write([]byte("name\nann\n"))
+		return err
+	}); err != nil {
+		t.Fatalf("register csv writer: %v", err)
+	}
+	if err := app.RegisterWriter("application/vnd.acme+json", func(c *Ctx, v any) error {
+		_, err := c.Write([]byte(`{"acme":true}`))
+		return err
+	}); err != nil {
+		t.Fatalf("register vendor writer: %v", err)
+	}
+	if err := app.RegisterReader("Application/CBOR", func(c *Ctx, v any) error {
+		*(v.(*string)) = "cbor"
+		return nil
+	}); err != nil {
+		t.Fatalf("register cbor reader: %v", err)
+	}
+	for _, contentType := range []string{"", "text", "*/*", "text/*"} {
+		if err := app.RegisterWriter(contentType, nil); err != ErrContentType {
+			t.Fatalf("%q: expected ErrContentType, got %v", contentType, err)
+		}
+	}
+
+	app.Post("/users", func(c *Ctx) (any, error) {
+		var name string
+		if err := c.TryParseBody(&name); err != nil {
+			return nil, err
+		}
+		return name, nil
+	})
+
+	tests := []struct {
+		contentType string
+		body        string
+		accept      string
+		code        int
+		ctype       string
+		want        string
+	}{
+		{contentType: "application/cbor", accept: "text/csv", code: http.StatusOK, ctype: "text/csv", want: "name\nann\n"},
+		{contentType: "application/cbor", accept: "application/vnd.acme+json, application/json;q=0.5", code: http.StatusOK, ctype: "application/vnd.acme+json", want: `{"acme":true}`},
+		{contentType: "application/cbor", accept: "text/*", code: http.StatusOK, ctype: "application/xml"},
+		{contentType: "application/cbor", accept: "*/*", code: http.StatusOK, ctype: "application/json", want: "\"cbor\"\n"},
+		{contentType: "application/cbor", accept: "application/vnd.other+json", code: http.StatusOK, ctype: "application/json", want: "\"cbor\"\n"},
+		{contentType: "application/vnd.other+json; charset=utf-8", body: `"ann"`, code: http.StatusOK, ctype: "application/json", want: "\"ann\"\n"},
+		{contentType: "text/plain", body: `ann`, code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Accept", tt.accept)
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%q %q: expected status %d, got %d", tt.contentType, tt.accept, tt.code, rec.Code)
+		}
+		if tt.ctype != "" && rec.Header().Get("Content-Type") != tt.ctype {
+			t.Fatalf("%q %q: expected Content-Type %q, got %q", tt.contentType, tt.accept, tt.ctype, rec.Header().Get("Content-Type"))
+		}
+		if tt.want != "" && rec.Body.String() != tt.want {
+			t.Fatalf("%q %q: expected body %q, got %q", tt.contentType, tt.accept, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestAcceptCacheSkipsLongHeaders(t *testing.T) {
+	t.Parallel()
+
+	var ac acceptCache
+	long := "application/json," + strings.Repeat("x", acceptCacheKeyMax)
+	ac.store(long, acceptResult{mt: mediaJSON})
+	if _, ok := ac.load(long); ok || ac.size.Load() != 0 {
+		t.Fatalf("expected a %d byte header not to be cached", len(long))
+	}
+
+	ac.store("application/xml, */*", acceptResult{mt: mediaXML})
+	if res, ok := ac.load("application/xml, */*"); !ok || res.mt != mediaXML {
+		t.Fatalf("expected a short header to be cached, got %v %v", res, ok)
+	}
+}
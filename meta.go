@@ -0,0 +1,73 @@
+package web
+
+// RouteOption attaches a metadata key and value to routes. Unlike
+// middleware it never wraps the handler; the values are reported by
+// Ctx.Route and Routes. Options are made by Meta, RequestBody and
+// ResponseBody.
+type RouteOption struct {
+	key   string
+	value any
+}
+
+// Meta returns a route option attaching the key and value, e.g.
+// app.Get("/users", h).With(web.Meta("tag", "users")).
+func Meta(key string, value any) RouteOption {
+	return RouteOption{key: key, value: value}
+}
+
+// With attaches the options to the route; they override the options of
+// its group and of the application.
+func (r *Route) With(opts ...RouteOption) *Route {
+	app := r.app
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	r.options = append(r.options, opts...)
+
+	// Copied, as requests may be reading the current map
+	meta := make(map[string]any, len(r.meta)+len(opts))
+	for k, v := range r.meta {
+		meta[k] = v
+	}
+	for _, opt := range opts {
+		meta[opt.key] = opt.value
+	}
+	r.meta = meta
+	return r
+}
+
+// With attaches the options to the routes registered on the group
+// afterwards, and to all of them once Build is called. Options of inner
+// groups override those of outer ones.
+func (g *RouteGroup) With(opts ...RouteOption) *RouteGroup {
+	g.options = append(g.options, opts...)
+	return g
+}
+
+// With attaches the options to the routes registered afterwards, and to all
+// of them once Build is called. Options of groups and routes override them.
+func (app *Application) With(opts ...RouteOption) {
+	app.options = append(app.options, opts...)
+}
+
+// metaFor collects the options of the application, of the groups from g
+// outward and of the route, innermost values winning, or returns nil if
+// there are none.
+func metaFor(app *Application, g *RouteGroup, route []RouteOption) map[string]any {
+	chains := [][]RouteOption{route}
+	for ; g != nil; g = g.parent {
+		chains = append(chains, g.options)
+	}
+	chains = append(chains, app.options)
+
+	var meta map[string]any
+	for i := len(chains) - 1; i >= 0; i-- {
+		for _, opt := range chains[i] {
+			if meta == nil {
+				meta = make(map[string]any)
+			}
+			meta[opt.key] = opt.value
+		}
+	}
+	return meta
+}
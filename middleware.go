@@ -109,7 +109,7 @@ func RecoverWithOptions(opts RecoverOptions) Middleware {
 					}
 
 					err = NewErrFn(status, body, func(w http.ResponseWriter, r *http.Request) error {
-						writeCodeByMedia(w, c.responseMediaType(), status)
+						c.writeCodeByMedia(c.responseMediaType(), status)
 						return c.write(body)
 					})
 				}
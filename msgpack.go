@@ -0,0 +1,912 @@
+package web
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessagePack (https://msgpack.org) codec for the application/x-msgpack
+// media type. Structs are encoded as maps keyed by field name, taken from
+// the msgpack tag or else the json tag, which may carry omitempty; fields
+// tagged "-" are left out and fields of embedded structs are promoted.
+// time.Time uses the timestamp extension.
+
+var (
+	errMsgPackShort = errors.New("msgpack: unexpected end of data")
+	errMsgPackDepth = errors.New("msgpack: exceeded max depth")
+)
+
+// msgpackMaxDepth bounds the nesting of arrays and maps.
+const msgpackMaxDepth = 10000
+
+// msgpackTimestamp is the extension type of timestamps.
+const msgpackTimestamp = -1
+
+var _msgpackBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// MarshalMsgPack returns the MessagePack encoding of v.
+func MarshalMsgPack(v any) ([]byte, error) {
+	return appendMsgPack(nil, v)
+}
+
+// UnmarshalMsgPack decodes the MessagePack data into the value v points to.
+// Values decoded into an interface are nil, bool, int64, uint64 (for
+// integers beyond int64), float64, string, []byte, []any, map[string]any
+// and time.Time.
+func UnmarshalMsgPack(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("UnmarshalMsgPack: %T is not a non-nil pointer", v)
+	}
+
+	d := msgpackDecoder{data: data}
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("msgpack: trailing data")
+	}
+	return nil
+}
+
+// readMsgPack decodes a MessagePack request body using a pooled buffer.
+func (c *Ctx) readMsgPack(val any) error {
+	buf := _bodyReadBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	_, err := buf.ReadFrom(c.r.Body)
+	if err == nil {
+		if buf.Len() == 0 {
+			err = io.EOF
+		} else {
+			err = UnmarshalMsgPack(buf.Bytes(), val)
+		}
+	}
+
+	buf.Reset()
+	_bodyReadBufferPool.Put(buf)
+	return err
+}
+
+// writeMsgPack Write MessagePack
+func (c *Ctx) writeMsgPack(val any) error {
+	if val == nil {
+		return nil
+	}
+
+	buf := _msgpackBufPool.Get().(*[]byte)
+	b, err := appendMsgPack((*buf)[:0], val)
+	if err == nil {
+		_, err = c.w.Write(b)
+	}
+	// Large buffers are left to the garbage collector
+	if cap(b) <= 64<<10 {
+		*buf = b[:0]
+		_msgpackBufPool.Put(buf)
+	}
+	return err
+}
+
+// appendMsgPack appends the encoding of v to b. Common types skip
+// reflection.
+func appendMsgPack(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		return appendMsgPackBool(b, v), nil
+	case string:
+		return appendMsgPackString(b, v), nil
+	case int:
+		return appendMsgPackInt(b, int64(v)), nil
+	case int64:
+		return appendMsgPackInt(b, v), nil
+	case uint64:
+		return appendMsgPackUint(b, v), nil
+	case float64:
+		return appendMsgPackFloat64(b, v), nil
+	case []byte:
+		return appendMsgPackBytes(b, v), nil
+	case time.Time:
+		return appendMsgPackTime(b, v), nil
+	default:
+		return appendMsgPackValue(b, reflect.ValueOf(v), 0)
+	}
+}
+
+func appendMsgPackValue(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	if depth > msgpackMaxDepth {
+		return b, errMsgPackDepth
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return append(b, 0xc0), nil
+	case reflect.Bool:
+		return appendMsgPackBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgPackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendMsgPackUint(b, v.Uint()), nil
+	case reflect.Float32:
+		b = append(b, 0xca)
+		return binary.BigEndian.AppendUint32(b, math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		return appendMsgPackFloat64(b, v.Float()), nil
+	case reflect.String:
+		return appendMsgPackString(b, v.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgPackValue(b, v.Elem(), depth+1)
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendMsgPackBytes(b, v.Bytes()), nil
+		}
+		return appendMsgPackArray(b, v, depth)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendMsgPackLen(b, v.Len(), -1, 0, 0xc4, 0xc5, 0xc6)
+			for i := 0; i < v.Len(); i++ {
+				b = append(b, byte(v.Index(i).Uint()))
+			}
+			return b, nil
+		}
+		return appendMsgPackArray(b, v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgPackMap(b, v, depth)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return appendMsgPackTime(b, v.Interface().(time.Time)), nil
+		}
+		return appendMsgPackStruct(b, v, depth)
+	default:
+		return b, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+}
+
+func appendMsgPackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgPackUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func appendMsgPackUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= 0x7f:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+	}
+}
+
+func appendMsgPackFloat64(b []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	b = appendMsgPackLen(b, len(s), 31, 0xa0, 0xd9, 0xda, 0xdb)
+	return append(b, s...)
+}
+
+func appendMsgPackBytes(b []byte, p []byte) []byte {
+	b = appendMsgPackLen(b, len(p), -1, 0, 0xc4, 0xc5, 0xc6)
+	return append(b, p...)
+}
+
+// appendMsgPackLen appends the header of a value of length n: the fix
+// format with n added if n is at most fixMax, else the 8, 16 or 32 bit
+// format. Arrays and maps have no 8 bit format, f8 is 0 then.
+func appendMsgPackLen(b []byte, n, fixMax int, fix, f8, f16, f32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fix|byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		return append(b, f8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, f16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, f32), uint32(n))
+	}
+}
+
+func appendMsgPackArray(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	n := v.Len()
+	b = appendMsgPackLen(b, n, 15, 0x90, 0, 0xdc, 0xdd)
+	var err error
+	for i := 0; i < n; i++ {
+		if b, err = appendMsgPackValue(b, v.Index(i), depth+1); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// appendMsgPackMap encodes a map, with string keys sorted like
+// encoding/json does.
+func appendMsgPackMap(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	b = appendMsgPackLen(b, v.Len(), 15, 0x80, 0, 0xde, 0xdf)
+
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+
+	var err error
+	for _, k := range keys {
+		if b, err = appendMsgPackValue(b, k, depth+1); err != nil {
+			return b, err
+		}
+		if b, err = appendMsgPackValue(b, v.MapIndex(k), depth+1); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+func appendMsgPackStruct(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	st := msgpackStructOf(v.Type())
+
+	n := 0
+	for i := range st.fields {
+		f := &st.fields[i]
+		if !f.omitEmpty || !isEmptyValue(v.FieldByIndex(f.index)) {
+			n++
+		}
+	}
+
+	b = appendMsgPackLen(b, n, 15, 0x80, 0, 0xde, 0xdf)
+	var err error
+	for i := range st.fields {
+		f := &st.fields[i]
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		b = appendMsgPackString(b, f.name)
+		if b, err = appendMsgPackValue(b, fv, depth+1); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// appendMsgPackTime encodes t with the smallest timestamp format.
+func appendMsgPackTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		b = append(b, 0xd6, 0xff)
+		return binary.BigEndian.AppendUint32(b, uint32(sec))
+	case sec>>34 == 0:
+		b = append(b, 0xd7, 0xff)
+		return binary.BigEndian.AppendUint64(b, nsec<<34|uint64(sec))
+	default:
+		b = append(b, 0xc7, 12, 0xff)
+		b = binary.BigEndian.AppendUint32(b, uint32(nsec))
+		return binary.BigEndian.AppendUint64(b, uint64(sec))
+	}
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// msgpackField is a struct field encoded as a map entry.
+type msgpackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// msgpackStruct holds the fields of a struct type.
+type msgpackStruct struct {
+	fields []msgpackField
+	byName map[string]int
+}
+
+var msgpackStructs sync.Map // reflect.Type -> *msgpackStruct
+
+func msgpackStructOf(t reflect.Type) *msgpackStruct {
+	if st, ok := msgpackStructs.Load(t); ok {
+		return st.(*msgpackStruct)
+	}
+
+	st := &msgpackStruct{byName: make(map[string]int)}
+	st.addFields(t, nil, 0)
+	actual, _ := msgpackStructs.LoadOrStore(t, st)
+	return actual.(*msgpackStruct)
+}
+
+func (st *msgpackStruct) addFields(t reflect.Type, index []int, depth int) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := st.byName[name]; ok {
+			continue
+		}
+		st.byName[name] = len(st.fields)
+		st.fields = append(st.fields, msgpackField{
+			name:      name,
+			index:     append(index[:len(index):len(index)], i),
+			omitEmpty: hasTagOption(opts, "omitempty"),
+		})
+	}
+
+	// Fields of embedded structs are promoted unless shadowed
+	if depth < 8 {
+		for _, sf := range embedded {
+			st.addFields(sf.Type, append(index[:len(index):len(index)], sf.Index...), depth+1)
+		}
+	}
+}
+
+// field returns the field named name, matching case-insensitively if there
+// is no exact match.
+func (st *msgpackStruct) field(name string) *msgpackField {
+	if i, ok := st.byName[name]; ok {
+		return &st.fields[i]
+	}
+	for i := range st.fields {
+		if strings.EqualFold(st.fields[i].name, name) {
+			return &st.fields[i]
+		}
+	}
+	return nil
+}
+
+const (
+	msgpackNil = iota
+	msgpackBool
+	msgpackInt
+	msgpackUint
+	msgpackFloat
+	msgpackStr
+	msgpackBin
+	msgpackArray
+	msgpackMap
+	msgpackExt
+)
+
+// msgpackHeader is the format of a value and what follows it: n bytes of
+// strings, binaries and extensions, n elements of arrays and n entries of
+// maps.
+type msgpackHeader struct {
+	kind int
+	n    int
+	i    int64
+	u    uint64
+	f    float64
+	ext  int8
+}
+
+type msgpackDecoder struct {
+	data []byte
+	off  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return nil, errMsgPackShort
+	}
+	p := d.data[d.off : d.off+n]
+	d.off += n
+	return p, nil
+}
+
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	p, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(p[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(p)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(p)), nil
+	default:
+		return binary.BigEndian.Uint64(p), nil
+	}
+}
+
+func (d *msgpackDecoder) header() (msgpackHeader, error) {
+	p, err := d.next(1)
+	if err != nil {
+		return msgpackHeader{}, err
+	}
+	c := p[0]
+
+	switch {
+	case c <= 0x7f:
+		return msgpackHeader{kind: msgpackInt, i: int64(c)}, nil
+	case c >= 0xe0:
+		return msgpackHeader{kind: msgpackInt, i: int64(int8(c))}, nil
+	case c <= 0x8f:
+		return d.sized(msgpackHeader{kind: msgpackMap, n: int(c & 0x0f)})
+	case c <= 0x9f:
+		return d.sized(msgpackHeader{kind: msgpackArray, n: int(c & 0x0f)})
+	case c <= 0xbf:
+		return d.sized(msgpackHeader{kind: msgpackStr, n: int(c & 0x1f)})
+	}
+
+	var (
+		h    msgpackHeader
+		u    uint64
+		size int
+	)
+	switch c {
+	case 0xc0:
+		return msgpackHeader{kind: msgpackNil}, nil
+	case 0xc2, 0xc3:
+		return msgpackHeader{kind: msgpackBool, u: uint64(c & 1)}, nil
+	case 0xc4, 0xc5, 0xc6:
+		h.kind, size = msgpackBin, 1<<(c-0xc4)
+	case 0xc7, 0xc8, 0xc9:
+		h.kind, size = msgpackExt, 1<<(c-0xc7)
+	case 0xca:
+		if u, err = d.uint(4); err != nil {
+			return h, err
+		}
+		return msgpackHeader{kind: msgpackFloat, f: float64(math.Float32frombits(uint32(u)))}, nil
+	case 0xcb:
+		if u, err = d.uint(8); err != nil {
+			return h, err
+		}
+		return msgpackHeader{kind: msgpackFloat, f: math.Float64frombits(u)}, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		if u, err = d.uint(1 << (c - 0xcc)); err != nil {
+			return h, err
+		}
+		if u > math.MaxInt64 {
+			return msgpackHeader{kind: msgpackUint, u: u}, nil
+		}
+		return msgpackHeader{kind: msgpackInt, i: int64(u)}, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size = 1 << (c - 0xd0)
+		if u, err = d.uint(size); err != nil {
+			return h, err
+		}
+		// Sign extend
+		shift := 64 - 8*size
+		return msgpackHeader{kind: msgpackInt, i: int64(u<<shift) >> shift}, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		if p, err = d.next(1); err != nil {
+			return h, err
+		}
+		return d.sized(msgpackHeader{kind: msgpackExt, n: 1 << (c - 0xd4), ext: int8(p[0])})
+	case 0xd9, 0xda, 0xdb:
+		h.kind, size = msgpackStr, 1<<(c-0xd9)
+	case 0xdc, 0xdd:
+		h.kind, size = msgpackArray, 2<<(c-0xdc)
+	case 0xde, 0xdf:
+		h.kind, size = msgpackMap, 2<<(c-0xde)
+	default:
+		return h, fmt.Errorf("msgpack: invalid format 0x%x", c)
+	}
+
+	if u, err = d.uint(size); err != nil {
+		return h, err
+	}
+	if u > math.MaxInt32 {
+		return h, errMsgPackShort
+	}
+	if h.kind == msgpackExt {
+		if p, err = d.next(1); err != nil {
+			return h, err
+		}
+		h.ext = int8(p[0])
+	}
+	h.n = int(u)
+	return d.sized(h)
+}
+
+// sized returns h, making sure the data can hold its n bytes, elements or
+// entries, each of which takes at least a byte.
+func (d *msgpackDecoder) sized(h msgpackHeader) (msgpackHeader, error) {
+	return h, d.check(h.n)
+}
+
+func (d *msgpackDecoder) check(n int) error {
+	if n > len(d.data)-d.off {
+		return errMsgPackShort
+	}
+	return nil
+}
+
+// skip skips the rest of the value of h.
+func (d *msgpackDecoder) skip(h msgpackHeader, depth int) error {
+	if depth > msgpackMaxDepth {
+		return errMsgPackDepth
+	}
+	switch h.kind {
+	case msgpackStr, msgpackBin, msgpackExt:
+		_, err := d.next(h.n)
+		return err
+	case msgpackArray, msgpackMap:
+		n := h.n
+		if h.kind == msgpackMap {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			eh, err := d.header()
+			if err != nil {
+				return err
+			}
+			if err := d.skip(eh, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) decode(v reflect.Value, depth int) error {
+	if depth > msgpackMaxDepth {
+		return errMsgPackDepth
+	}
+	h, err := d.header()
+	if err != nil {
+		return err
+	}
+	return d.decodeValue(h, v, depth)
+}
+
+func (d *msgpackDecoder) decodeValue(h msgpackHeader, v reflect.Value, depth int) error {
+	if h.kind == msgpackNil {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(h, v.Elem(), depth+1)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		x, err := d.decodeAny(h, depth)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+
+	switch h.kind {
+	case msgpackBool:
+		if v.Kind() == reflect.Bool {
+			v.SetBool(h.u == 1)
+			return nil
+		}
+	case msgpackInt:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !v.OverflowInt(h.i) {
+				v.SetInt(h.i)
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if h.i >= 0 && !v.OverflowUint(uint64(h.i)) {
+				v.SetUint(uint64(h.i))
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(h.i))
+			return nil
+		}
+	case msgpackUint:
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+			if !v.OverflowUint(h.u) {
+				v.SetUint(h.u)
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(h.u))
+			return nil
+		}
+	case msgpackFloat:
+		if k := v.Kind(); k == reflect.Float32 || k == reflect.Float64 {
+			v.SetFloat(h.f)
+			return nil
+		}
+	case msgpackStr, msgpackBin:
+		p, err := d.next(h.n)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(p))
+			return nil
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), p...))
+			return nil
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			n := reflect.Copy(v, reflect.ValueOf(p))
+			for i := n; i < v.Len(); i++ {
+				v.Index(i).SetZero()
+			}
+			return nil
+		}
+		d.off -= h.n
+	case msgpackArray:
+		return d.decodeArray(h, v, depth)
+	case msgpackMap:
+		return d.decodeMap(h, v, depth)
+	case msgpackExt:
+		if h.ext == msgpackTimestamp && v.Type() == timeType {
+			t, err := d.decodeTime(h)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("msgpack: cannot decode %s into %s", msgpackKindNames[h.kind], v.Type())
+}
+
+var msgpackKindNames = [...]string{"nil", "bool", "integer", "integer", "float", "string", "binary", "array", "map", "extension"}
+
+func (d *msgpackDecoder) decodeArray(h msgpackHeader, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Cap() >= h.n {
+			v.SetLen(h.n)
+		} else {
+			v.Set(reflect.MakeSlice(v.Type(), h.n, h.n))
+		}
+		for i := 0; i < h.n; i++ {
+			if err := d.decode(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < h.n; i++ {
+			if i >= v.Len() {
+				eh, err := d.header()
+				if err != nil {
+					return err
+				}
+				if err := d.skip(eh, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		for i := h.n; i < v.Len(); i++ {
+			v.Index(i).SetZero()
+		}
+		return nil
+	}
+	return fmt.Errorf("msgpack: cannot decode array into %s", v.Type())
+}
+
+func (d *msgpackDecoder) decodeMap(h msgpackHeader, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Map:
+		t := v.Type()
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, h.n))
+		}
+		for i := 0; i < h.n; i++ {
+			key := reflect.New(t.Key()).Elem()
+			if err := d.decode(key, depth+1); err != nil {
+				return err
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := d.decode(elem, depth+1); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return nil
+	case reflect.Struct:
+		st := msgpackStructOf(v.Type())
+		for i := 0; i < h.n; i++ {
+			kh, err := d.header()
+			if err != nil {
+				return err
+			}
+			if kh.kind != msgpackStr {
+				return errors.New("msgpack: struct keys must be strings")
+			}
+			name, err := d.next(kh.n)
+			if err != nil {
+				return err
+			}
+
+			// Unknown fields are skipped
+			f := st.field(string(name))
+			if f == nil {
+				vh, err := d.header()
+				if err != nil {
+					return err
+				}
+				if err := d.skip(vh, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.FieldByIndex(f.index), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("msgpack: cannot decode map into %s", v.Type())
+}
+
+func (d *msgpackDecoder) decodeTime(h msgpackHeader) (time.Time, error) {
+	p, err := d.next(h.n)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch h.n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(p)), 0), nil
+	case 8:
+		u := binary.BigEndian.Uint64(p)
+		return time.Unix(int64(u&(1<<34-1)), int64(u>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(p[4:])), int64(binary.BigEndian.Uint32(p))), nil
+	}
+	return time.Time{}, errors.New("msgpack: invalid timestamp")
+}
+
+func (d *msgpackDecoder) decodeAny(h msgpackHeader, depth int) (any, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errMsgPackDepth
+	}
+
+	switch h.kind {
+	case msgpackBool:
+		return h.u == 1, nil
+	case msgpackInt:
+		return h.i, nil
+	case msgpackUint:
+		return h.u, nil
+	case msgpackFloat:
+		return h.f, nil
+	case msgpackStr:
+		p, err := d.next(h.n)
+		return string(p), err
+	case msgpackBin:
+		p, err := d.next(h.n)
+		return append([]byte(nil), p...), err
+	case msgpackArray:
+		a := make([]any, h.n)
+		for i := range a {
+			eh, err := d.header()
+			if err != nil {
+				return nil, err
+			}
+			if a[i], err = d.decodeAny(eh, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case msgpackMap:
+		m := make(map[string]any, h.n)
+		for i := 0; i < h.n; i++ {
+			kh, err := d.header()
+			if err != nil {
+				return nil, err
+			}
+			if kh.kind != msgpackStr {
+				return nil, errors.New("msgpack: map keys decoded into an interface must be strings")
+			}
+			key, err := d.next(kh.n)
+			if err != nil {
+				return nil, err
+			}
+			vh, err := d.header()
+			if err != nil {
+				return nil, err
+			}
+			if m[string(key)], err = d.decodeAny(vh, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case msgpackExt:
+		if h.ext == msgpackTimestamp {
+			return d.decodeTime(h)
+		}
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", h.ext)
+	}
+	return nil, nil
+}
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalMsgPack(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   any
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"true", true, []byte{0xc3}},
+		{"positive fixint", 5, []byte{0x05}},
+		{"negative fixint", -3, []byte{0xfd}},
+		{"uint8", 200, []byte{0xcc, 0xc8}},
+		{"int8", -100, []byte{0xd0, 0x9c}},
+		{"uint16", uint16(1000), []byte{0xcd, 0x03, 0xe8}},
+		{"int32", int32(-40000), []byte{0xd2, 0xff, 0xff, 0x63, 0xc0}},
+		{"uint64", uint64(math.MaxUint64), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"float32", float32(1.5), []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}},
+		{"float64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"fixstr", "hi", []byte{0xa2, 'h', 'i'}},
+		{"str8", strings.Repeat("a", 32), append([]byte{0xd9, 32}, strings.Repeat("a", 32)...)},
+		{"bin", []byte{1, 2}, []byte{0xc4, 2, 1, 2}},
+		{"fixarray", []int{1, 2}, []byte{0x92, 1, 2}},
+		{"nil slice", []int(nil), []byte{0xc0}},
+		{"sorted map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 1, 0xa1, 'b', 2}},
+		{"timestamp32", time.Unix(1, 0), []byte{0xd6, 0xff, 0, 0, 0, 1}},
+		{"struct", struct {
+			ID   int    `json:"id"`
+			Name string `msgpack:"n,omitempty"`
+			Skip bool   `json:"-"`
+		}{ID: 1}, []byte{0x81, 0xa2, 'i', 'd', 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalMsgPack(tt.in)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("expected % x, got % x", tt.want, got)
+			}
+		})
+	}
+}
+
+type msgpackBase struct {
+	ID      uint64    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type msgpackItem struct {
+	msgpackBase
+	Name    string            `json:"name"`
+	Price   float64           `json:"price"`
+	Count   *int              `json:"count,omitempty"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]string `json:"attrs"`
+	Data    []byte            `json:"data"`
+	Digest  [4]byte           `json:"digest"`
+	Parts   []*msgpackItem    `json:"parts,omitempty"`
+	Extra   any               `json:"extra"`
+	private int
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	count := 3
+	in := msgpackItem{
+		msgpackBase: msgpackBase{ID: 1 << 40, Created: time.Unix(1700000000, 123456789)},
+		Name:        "widget",
+		Price:       -9.25,
+		Count:       &count,
+		Tags:        []string{"a", "b"},
+		Attrs:       map[string]string{"color": "red"},
+		Data:        []byte{0, 1, 2},
+		Digest:      [4]byte{9, 8, 7, 6},
+		Parts:       []*msgpackItem{{msgpackBase: msgpackBase{Created: time.Unix(-1, 0)}, Name: "bolt"}},
+		Extra:       map[string]any{"n": int64(-70000), "list": []any{true, nil, "x", 2.5}},
+	}
+
+	data, err := MarshalMsgPack(in)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var out msgpackItem
+	if err := UnmarshalMsgPack(data, &out); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !out.Created.Equal(in.Created) || !out.Parts[0].Created.Equal(in.Parts[0].Created) {
+		t.Fatalf("expected times %v and %v, got %v and %v", in.Created, in.Parts[0].Created, out.Created, out.Parts[0].Created)
+	}
+	out.Created, out.Parts[0].Created = in.Created, in.Parts[0].Created
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+}
+
+func TestUnmarshalMsgPack(t *testing.T) {
+	t.Parallel()
+
+	var target struct {
+		Name string `json:"name"`
+		Age  uint8  `json:"age"`
+	}
+
+	// Unknown fields are skipped and names match case-insensitively
+	data := []byte{0x83, 0xa4, 'N', 'A', 'M', 'E', 0xa1, 'x', 0xa5, 'o', 't', 'h', 'e', 'r', 0x92, 1, 0x81, 0xa1, 'k', 0xc0, 0xa3, 'a', 'g', 'e', 0xcc, 200}
+	if err := UnmarshalMsgPack(data, &target); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if target.Name != "x" || target.Age != 200 {
+		t.Fatalf("expected x and 200, got %q and %d", target.Name, target.Age)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"truncated", []byte{0x81, 0xa4, 'n', 'a'}, "unexpected end"},
+		{"trailing", []byte{0x80, 0x80}, "trailing data"},
+		{"invalid format", []byte{0xc1}, "invalid format"},
+		{"overflow", []byte{0x81, 0xa3, 'a', 'g', 'e', 0xcd, 0x01, 0x00}, "cannot decode integer into uint8"},
+		{"wrong type", []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0x01}, "cannot decode integer into string"},
+		{"huge length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, "unexpected end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := UnmarshalMsgPack(tt.data, &target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestServeHTTPMsgPack(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age"`
+	}
+
+	app := New()
+	app.AutoValidate = true
+	app.Post("/users", func(c *Ctx) (any, error) {
+		var u user
+		if err := c.TryParseBody(&u); err != nil {
+			return nil, err
+		}
+		u.Age++
+		return u, nil
+	})
+
+	tests := []struct {
+		name   string
+		in     user
+		accept string
+		code   int
+		want   any
+	}{
+		{"decode and encode", user{Name: "sam", Age: 41}, "application/x-msgpack", http.StatusOK, user{Name: "sam", Age: 42}},
+		{"vendor name", user{Name: "sam", Age: 1}, "application/vnd.msgpack", http.StatusOK, user{Name: "sam", Age: 2}},
+		{"error body", user{Age: 1}, "application/x-msgpack", http.StatusUnprocessableEntity, ErrorBody{
+			Code:    http.StatusUnprocessableEntity,
+			Message: ErrUnprocessableEntity.Error(),
+			Errors:  []FieldError{{Pointer: "/body/name", Message: "is required"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := MarshalMsgPack(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/msgpack")
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %q", tt.code, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/x-msgpack" {
+				t.Fatalf("expected application/x-msgpack, got %q", got)
+			}
+
+			got := reflect.New(reflect.TypeOf(tt.want))
+			if err := UnmarshalMsgPack(rec.Body.Bytes(), got.Interface()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got.Elem().Interface(), tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got.Elem().Interface())
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI is an OpenAPI 3.1 document, as generated by Application.OpenAPI.
+type OpenAPI struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       OpenAPIInfo            `json:"info"`
+	Paths      map[string]OpenAPIPath `json:"paths"`
+	Components *OpenAPIComponents     `json:"components,omitempty"`
+}
+
+// OpenAPIInfo describes the API of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPath maps the lower case methods of a path to their operations.
+type OpenAPIPath map[string]*OpenAPIOperation
+
+// OpenAPIOperation describes a route.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter describes a path, query, header or cookie param.
+type OpenAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody describes the request body of an operation.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse describes a response of an operation.
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType holds the schema of a body.
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIComponents holds the schemas referenced by the document.
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Meta keys read by Application.OpenAPI. Summary and description are
+// strings, deprecated a bool, and tags either a string or a []string.
+const (
+	MetaTag         = "tag"
+	MetaTags        = "tags"
+	MetaSummary     = "summary"
+	MetaDescription = "description"
+	MetaDeprecated  = "deprecated"
+
+	metaRequestBody    = "openapi.request"
+	metaResponsePrefix = "openapi.response."
+	metaHidden         = "openapi.hidden"
+)
+
+// RequestBody returns a route option documenting the JSON request body of
+// the route with the type of v, e.g. web.RequestBody(User{}).
+func RequestBody(v any) RouteOption {
+	return Meta(metaRequestBody, reflect.TypeOf(v))
+}
+
+// ResponseBody returns a route option documenting the JSON response of the
+// route for status with the type of v. A nil v documents a response without
+// a body.
+func ResponseBody(status int, v any) RouteOption {
+	return Meta(metaResponsePrefix+strconv.Itoa(status), reflect.TypeOf(v))
+}
+
+// openAPIMethods are the methods an OpenAPI path item can hold.
+var openAPIMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// OpenAPI generates an OpenAPI 3.1 document from the registered routes.
+// Path params are documented with the schema of their constraint, bodies
+// with the types given by RequestBody and ResponseBody, and operations with
+// the route name as operation id and the tag, summary, description and
+// deprecated metadata. Host routes, Any routes and custom methods are left
+// out, and so are routes of API versions other than info.Version.
+func (app *Application) OpenAPI(info OpenAPIInfo) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]OpenAPIPath),
+	}
+
+	b := newSchemaBuilder()
+	ids := make(map[string]bool)
+	for _, r := range app.Routes() {
+		if r.Host != "" || !openAPIMethods[r.Method] || r.Meta[metaHidden] != nil {
+			continue
+		}
+		if r.Version != "" && r.Version != info.Version {
+			continue
+		}
+		for _, path := range expandOptional(r.Path) {
+			p, params := openAPIPath(path)
+			item := doc.Paths[p]
+			if item == nil {
+				item = make(OpenAPIPath)
+				doc.Paths[p] = item
+			}
+			op := b.operation(r)
+			op.Parameters = params
+			// Operation ids are unique, for Match routes and optional
+			// params only the first operation gets the name
+			if id := op.OperationID; ids[id] {
+				op.OperationID = ""
+			} else if id != "" {
+				ids[id] = true
+			}
+			item[strings.ToLower(r.Method)] = op
+		}
+	}
+
+	if len(b.components) > 0 {
+		doc.Components = &OpenAPIComponents{Schemas: b.components}
+	}
+	return doc
+}
+
+// ServeOpenAPI registers a GET route at path serving the OpenAPI document
+// of the application as JSON. The document is generated per request, so it
+// covers routes registered later; its own route is left out.
+func (app *Application) ServeOpenAPI(path string, info OpenAPIInfo) *Route {
+	return app.Get(path, func(c *Ctx) (any, error) {
+		c.SetContentType("application/json")
+		c.WriteHeader(http.StatusOK)
+		return nil, c.writeJSON(app.OpenAPI(info))
+	}).With(Meta(metaHidden, true))
+}
+
+// openAPIPath converts a route pattern without optional params to an
+// OpenAPI path, e.g. "/users/:id<int>" to "/users/{id}", along with its
+// path params.
+func openAPIPath(path string) (string, []OpenAPIParameter) {
+	var (
+		sb     strings.Builder
+		params []OpenAPIParameter
+	)
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			sb.WriteString(path)
+			break
+		}
+		sb.WriteString(path[:i])
+		path = path[i+len(wildcard):]
+
+		name, constraint := wildcard[1:], ""
+		if wildcard[0] == ':' {
+			name, constraint = splitConstraint(wildcard)
+		}
+		sb.WriteString("{" + name + "}")
+		params = append(params, OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   constraintSchema(constraint),
+		})
+	}
+	return sb.String(), params
+}
+
+// constraintSchema returns the schema of the values a param constraint
+// accepts.
+func constraintSchema(constraint string) *Schema {
+	switch constraint {
+	case "":
+		return &Schema{Type: "string"}
+	case "int":
+		return &Schema{Type: "integer"}
+	case "uint":
+		return &Schema{Type: "integer", Minimum: new(float64)}
+	case "float":
+		return &Schema{Type: "number"}
+	case "alpha":
+		return &Schema{Type: "string", Pattern: "^[A-Za-z]+$"}
+	case "alnum":
+		return &Schema{Type: "string", Pattern: "^[A-Za-z0-9]+$"}
+	case "hex":
+		return &Schema{Type: "string", Pattern: "^[0-9A-Fa-f]+$"}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "date":
+		return &Schema{Type: "string", Format: "date"}
+	default:
+		return &Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+	}
+}
+
+// operation documents the route r.
+func (b *schemaBuilder) operation(r RouteInfo) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: r.Name,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	op.Summary, _ = r.Meta[MetaSummary].(string)
+	op.Description, _ = r.Meta[MetaDescription].(string)
+	op.Deprecated, _ = r.Meta[MetaDeprecated].(bool)
+	if tag, ok := r.Meta[MetaTag].(string); ok {
+		op.Tags = append(op.Tags, tag)
+	}
+	if tags, ok := r.Meta[MetaTags].([]string); ok {
+		op.Tags = append(op.Tags, tags...)
+	}
+
+	if t, ok := r.Meta[metaRequestBody].(reflect.Type); ok {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{"application/json": {Schema: b.schema(t)}},
+		}
+	}
+
+	for key, v := range r.Meta {
+		status, ok := strings.CutPrefix(key, metaResponsePrefix)
+		if !ok {
+			continue
+		}
+		code, _ := strconv.Atoi(status)
+		resp := &OpenAPIResponse{Description: http.StatusText(code)}
+		if t, ok := v.(reflect.Type); ok {
+			resp.Content = map[string]OpenAPIMediaType{"application/json": {Schema: b.schema(t)}}
+		}
+		op.Responses[status] = resp
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["default"] = &OpenAPIResponse{Description: "Default response"}
+	}
+	return op
+}
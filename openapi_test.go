@@ -0,0 +1,97 @@
+package web
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type openAPIBase struct {
+	ID      uint64    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type openAPIUser struct {
+	openAPIBase
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Age     int32             `json:"age,string"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Manager *openAPIUser      `json:"manager,omitempty"`
+	secret  string
+	Skip    string `json:"-"`
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	app := New()
+	ok := func(c *Ctx) (any, error) { return nil, nil }
+	users := app.Group("/users").With(Meta(MetaTag, "users"))
+	users.Get("/:id<uint>", ok).With(ResponseBody(http.StatusOK, openAPIUser{}), Meta(MetaSummary, "Show a user")).Name("user.show")
+	users.Post("", ok).With(RequestBody(openAPIUser{}), ResponseBody(http.StatusCreated, &openAPIUser{}), ResponseBody(http.StatusConflict, nil))
+	app.Get("/reports/:year<int>/:month?", ok)
+	app.Get("/files/*path", ok)
+	app.Any("/any", ok)
+	app.Host("api.example.com").Get("/ping", ok)
+	app.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "Test", Version: "1.0.0"})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON document, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var doc OpenAPI
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Test" {
+		t.Fatalf("unexpected document header: %+v", doc)
+	}
+
+	paths := slices.Sorted(maps.Keys(doc.Paths))
+	want := []string{"/files/{path}", "/reports/{year}", "/reports/{year}/{month}", "/users", "/users/{id}"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected paths %v, got %v", want, paths)
+	}
+
+	show := doc.Paths["/users/{id}"]["get"]
+	if show == nil || show.OperationID != "user.show" || show.Summary != "Show a user" || !reflect.DeepEqual(show.Tags, []string{"users"}) {
+		t.Fatalf("unexpected operation: %+v", show)
+	}
+	if len(show.Parameters) != 1 || show.Parameters[0].Name != "id" || show.Parameters[0].In != "path" ||
+		show.Parameters[0].Schema.Type != "integer" || *show.Parameters[0].Schema.Minimum != 0 {
+		t.Fatalf("unexpected params: %+v", show.Parameters)
+	}
+	if got := show.Responses["200"].Content["application/json"].Schema.Ref; got != "#/components/schemas/openAPIUser" {
+		t.Fatalf("expected response schema reference, got %q", got)
+	}
+
+	create := doc.Paths["/users"]["post"]
+	if create.RequestBody == nil || create.Responses["201"] == nil || create.Responses["409"].Content != nil {
+		t.Fatalf("unexpected create operation: %+v", create)
+	}
+
+	user := doc.Components.Schemas["openAPIUser"]
+	props := slices.Sorted(maps.Keys(user.Properties))
+	if want := []string{"age", "created", "email", "id", "labels", "manager", "name", "tags"}; !reflect.DeepEqual(props, want) {
+		t.Fatalf("expected properties %v, got %v", want, props)
+	}
+	if want := []string{"age", "created", "id", "name"}; !reflect.DeepEqual(slices.Sorted(slices.Values(user.Required)), want) {
+		t.Fatalf("expected required %v, got %v", want, user.Required)
+	}
+	if user.Properties["age"].Type != "string" || user.Properties["created"].Format != "date-time" ||
+		user.Properties["manager"].Ref != "#/components/schemas/openAPIUser" ||
+		user.Properties["labels"].AdditionalProperties.Type != "string" {
+		t.Fatalf("unexpected property schemas: %+v", user.Properties)
+	}
+
+	if op := doc.Paths["/reports/{year}"]["get"]; op.Responses["default"] == nil || op.Parameters[0].Schema.Type != "integer" {
+		t.Fatalf("unexpected report operation: %+v", op)
+	}
+}
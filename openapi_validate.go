@@ -0,0 +1,800 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// queryConventions are the schemas of the query params of const.go,
+// applied when an operation does not describe them itself.
+var queryConventions = map[string]map[string]any{
+	QueryFilter:  {"type": "string"},
+	QueryOrderBy: {"type": "string"},
+	QueryPage:    {"type": "integer", "minimum": json.Number("1")},
+	QueryLimit:   {"type": "integer", "minimum": json.Number("1")},
+}
+
+// maxSchemaDepth bounds the nesting of schemas checked against a value, so
+// that a schema referencing itself through allOf, anyOf or oneOf fails the
+// value instead of overflowing the stack.
+const maxSchemaDepth = 64
+
+// openAPIValidator validates requests against the operations of an OpenAPI
+// document. The document is kept as decoded JSON, so that any OpenAPI 3.x
+// schema can be checked, and its paths are matched with a route tree per
+// method.
+type openAPIValidator struct {
+	doc      map[string]any
+	trees    map[string]*node
+	ops      map[*Route]*apiOperation
+	params   paramsPool // of the path params of lookups
+	maxBody  int64
+	patterns map[string]*regexp.Regexp // compiled when the document is loaded
+}
+
+// apiOperation is an operation of the document.
+type apiOperation struct {
+	names        []string // path params by position
+	params       []apiParam
+	body         any // schema of the JSON request body
+	hasBody      bool
+	bodyTypes    []string // media types of the request body, sorted
+	bodyRequired bool
+}
+
+type apiParam struct {
+	name     string
+	in       string
+	required bool
+	explode  bool
+	schema   any
+}
+
+// OpenAPIValidatorOptions configures OpenAPIValidatorWithOptions.
+type OpenAPIValidatorOptions struct {
+	// MaxBodyBytes limits the JSON request bodies read for validation, 10 MiB
+	// if zero. Larger bodies fail with ErrRequestEntityTooLarge.
+	MaxBodyBytes int64
+}
+
+// OpenAPIValidator returns middleware validating requests against the local
+// OpenAPI 3 JSON document file. Requests matching an operation of the
+// document have their path params, query, headers, cookies and JSON body
+// checked against the schemas; the filter, orderBy, page and limit query
+// params follow the conventions of this package unless the operation
+// describes them. Failures are returned as an *InvalidError, i.e.
+// ErrInvalid with a pointer to every invalid field. Requests the document
+// does not describe pass unchecked.
+func OpenAPIValidator(file string) (Middleware, error) {
+	return OpenAPIValidatorWithOptions(file, OpenAPIValidatorOptions{})
+}
+
+// OpenAPIValidatorWithOptions is OpenAPIValidator configured by opts.
+func OpenAPIValidatorWithOptions(file string, opts OpenAPIValidatorOptions) (Middleware, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPIValidator: %w", err)
+	}
+	v, err := newOpenAPIValidator(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxBodyBytes > 0 {
+		v.maxBody = opts.MaxBodyBytes
+	}
+	return v.middleware, nil
+}
+
+func newOpenAPIValidator(data []byte) (v *openAPIValidator, err error) {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("OpenAPIValidator: %w", err)
+	}
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("OpenAPIValidator: unsupported OpenAPI version %q", version)
+	}
+
+	v = &openAPIValidator{
+		doc:      doc,
+		trees:    make(map[string]*node),
+		ops:      make(map[*Route]*apiOperation),
+		maxBody:  10 << 20,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	if err := v.compilePatterns(doc); err != nil {
+		return nil, err
+	}
+
+	// Conflicting paths panic in the tree like conflicting routes
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, fmt.Errorf("OpenAPIValidator: %v", r)
+		}
+	}()
+
+	paths, _ := doc["paths"].(map[string]any)
+	maxParams := 0
+	for template, item := range paths {
+		item, _ := v.resolve(item).(map[string]any)
+		pattern, names, ok := routePattern(template)
+		if !ok {
+			return nil, fmt.Errorf("OpenAPIValidator: unsupported path %q", template)
+		}
+		maxParams = max(maxParams, len(names))
+
+		shared := v.parameters(item["parameters"], nil)
+		for method, op := range item {
+			method = strings.ToUpper(method)
+			if !openAPIMethods[method] {
+				continue
+			}
+			op, _ := v.resolve(op).(map[string]any)
+			o := &apiOperation{
+				names:  names,
+				params: v.parameters(op["parameters"], shared),
+			}
+			v.requestBody(o, op["requestBody"])
+
+			root := v.trees[method]
+			if root == nil {
+				root = new(node)
+				v.trees[method] = root
+			}
+			r := &Route{path: template, params: names}
+			root.addRoute(pattern, func(c *Ctx) (any, error) { return nil, nil }).route = r
+			v.ops[r] = o
+		}
+	}
+	v.params.max.Store(uint32(maxParams))
+
+	return v, nil
+}
+
+// routePattern converts an OpenAPI path template to a route pattern with
+// params named by position, e.g. "/users/{id}" to "/users/:p0".
+func routePattern(template string) (pattern string, names []string, ok bool) {
+	var sb strings.Builder
+	for i := 0; i < len(template); i++ {
+		switch c := template[i]; c {
+		case '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 2 {
+				return "", nil, false
+			}
+			sb.WriteString(":p" + strconv.Itoa(len(names)))
+			names = append(names, template[i+1:i+end])
+			i += end
+		case '}', ':', '*':
+			return "", nil, false
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), names, len(template) > 0 && template[0] == '/'
+}
+
+// parameters returns the params of list, replacing those of shared with the
+// same name and location.
+func (v *openAPIValidator) parameters(list any, shared []apiParam) []apiParam {
+	params := append([]apiParam(nil), shared...)
+	items, _ := list.([]any)
+	for _, item := range items {
+		m, _ := v.resolve(item).(map[string]any)
+		name, _ := m["name"].(string)
+		in, _ := m["in"].(string)
+		if name == "" || in == "" {
+			continue
+		}
+		p := apiParam{name: name, in: in, schema: m["schema"], explode: true}
+		p.required, _ = m["required"].(bool)
+		if explode, ok := m["explode"].(bool); ok {
+			p.explode = explode
+		} else if style, _ := m["style"].(string); style != "" && style != "form" {
+			p.explode = false
+		}
+		if in == "header" {
+			p.name = strings.ToLower(name)
+		}
+
+		replaced := false
+		for i := range params {
+			if params[i].name == p.name && params[i].in == p.in {
+				params[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// requestBody sets the JSON body schema of o from the request body object rb.
+func (v *openAPIValidator) requestBody(o *apiOperation, rb any) {
+	m, _ := v.resolve(rb).(map[string]any)
+	if m == nil {
+		return
+	}
+	o.bodyRequired, _ = m["required"].(bool)
+	content, _ := m["content"].(map[string]any)
+	for mt, media := range content {
+		o.bodyTypes = append(o.bodyTypes, strings.ToLower(mt))
+		if isJSONMediaType(mt) && !o.hasBody {
+			media, _ := v.resolve(media).(map[string]any)
+			o.body, o.hasBody = media["schema"], true
+		}
+	}
+	sort.Strings(o.bodyTypes)
+}
+
+// acceptsBody reports whether the request body may have the media type mt.
+func (op *apiOperation) acceptsBody(mt string) bool {
+	if op.hasBody && isJSONMediaType(mt) {
+		return true
+	}
+	main, sub, _ := strings.Cut(mt, "/")
+	for _, t := range op.bodyTypes {
+		typ, _ := splitMediaType(t)
+		if rangeMain, rangeSub, ok := strings.Cut(typ, "/"); ok && mediaRangeMatch(rangeMain, rangeSub, main, sub) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSONMediaType(mt string) bool {
+	mt, _, _ = strings.Cut(mt, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// resolve follows the local $ref of x, if any.
+func (v *openAPIValidator) resolve(x any) any {
+	for depth := 0; depth < 32; depth++ {
+		m, ok := x.(map[string]any)
+		if !ok {
+			return x
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return x
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil
+		}
+		x = any(v.doc)
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch cur := x.(type) {
+			case map[string]any:
+				x = cur[token]
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(cur) {
+					return nil
+				}
+				x = cur[i]
+			default:
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (v *openAPIValidator) middleware(next Next) Next {
+	return func(c *Ctx) (any, error) {
+		root := v.trees[c.Method()]
+		if root == nil {
+			return next(c)
+		}
+
+		leaf, ps := root.match(c.Path(), &v.params, nil)
+		if leaf == nil {
+			v.params.put(ps)
+			return next(c)
+		}
+
+		op := v.ops[leaf.route]
+		fields := v.checkParams(c, op, ps)
+		v.params.put(ps)
+
+		fields, err := v.checkBody(c, op, fields)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > 0 {
+			sort.SliceStable(fields, func(i, j int) bool {
+				return fields[i].Pointer < fields[j].Pointer
+			})
+			return nil, NewInvalidError(fields)
+		}
+		return next(c)
+	}
+}
+
+func (v *openAPIValidator) checkParams(c *Ctx, op *apiOperation, ps *Params) []FieldError {
+	var fields []FieldError
+	query := c.QueryValues()
+
+	for _, p := range op.params {
+		var values []string
+		switch p.in {
+		case "path":
+			for i, name := range op.names {
+				if name == p.name && ps != nil && i < len(*ps) {
+					values = []string{(*ps)[i].Value}
+				}
+			}
+		case "query":
+			values = query[p.name]
+		case "header":
+			values = c.r.Header.Values(p.name)
+		case "cookie":
+			if cookie, err := c.r.Cookie(p.name); err == nil {
+				values = []string{cookie.Value}
+			}
+		default:
+			continue
+		}
+
+		pointer := "/" + p.in + "/" + escapePointer(p.name)
+		if len(values) == 0 {
+			if p.required || p.in == "path" {
+				fields = append(fields, FieldError{Pointer: pointer, Message: "is required"})
+			}
+			continue
+		}
+
+		schema := p.schema
+		if schema == nil && p.in == "query" {
+			if conv, ok := queryConventions[p.name]; ok {
+				schema = conv
+			}
+		}
+		fields = v.checkValues(schema, values, p.explode, pointer, fields)
+	}
+
+	for name, schema := range queryConventions {
+		if values := query[name]; len(values) > 0 && !op.describes("query", name) {
+			fields = v.checkValues(schema, values, true, "/query/"+name, fields)
+		}
+	}
+	return fields
+}
+
+func (op *apiOperation) describes(in, name string) bool {
+	for _, p := range op.params {
+		if p.in == in && p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValues converts the string values of a param to the type of its
+// schema and validates them.
+func (v *openAPIValidator) checkValues(schema any, values []string, explode bool, pointer string, fields []FieldError) []FieldError {
+	s, _ := v.resolve(schema).(map[string]any)
+	if schemaHasType(s, "array") {
+		if !explode && len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]any, len(values))
+		for i, value := range values {
+			items[i] = v.paramValue(s["items"], value)
+		}
+		return v.validate(s, items, pointer, 0, fields)
+	}
+	return v.validate(s, v.paramValue(s, values[0]), pointer, 0, fields)
+}
+
+// paramValue converts a param value to the JSON value it stands for, or
+// leaves it a string for the schema to reject.
+func (v *openAPIValidator) paramValue(schema any, value string) any {
+	s, _ := v.resolve(schema).(map[string]any)
+	switch {
+	case schemaHasType(s, "integer"), schemaHasType(s, "number"):
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case schemaHasType(s, "boolean"):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// checkBody checks that the request has a body of a documented media type
+// and validates JSON bodies, which stay readable for the handler. Only JSON
+// bodies are read, failing with ErrRequestEntityTooLarge over maxBody; any
+// other body passes through unread.
+func (v *openAPIValidator) checkBody(c *Ctx, op *apiOperation, fields []FieldError) ([]FieldError, error) {
+	if len(op.bodyTypes) == 0 && !op.bodyRequired {
+		return fields, nil
+	}
+
+	if !requestHasBody(c.r) {
+		if op.bodyRequired {
+			fields = append(fields, FieldError{Pointer: "/body", Message: "is required"})
+		}
+		return fields, nil
+	}
+
+	mt, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	if len(op.bodyTypes) > 0 && !op.acceptsBody(mt) {
+		return append(fields, FieldError{Pointer: "/header/content-type", Message: "must be " + strings.Join(op.bodyTypes, " or ")}), nil
+	}
+	if !op.hasBody || !isJSONMediaType(mt) {
+		return fields, nil
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c, c.r.Body, v.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fields, ErrRequestEntityTooLarge
+		}
+		return fields, err
+	}
+	c.r.Body.Close()
+	c.r.Body = io.NopCloser(bytes.NewReader(data))
+
+	var body any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return append(fields, FieldError{Pointer: "/body", Message: "must be valid JSON"}), nil
+	}
+	return v.validate(op.body, body, "/body", 0, fields), nil
+}
+
+// requestHasBody reports whether r has a non-empty body, reading its first
+// byte if the length is unknown. The byte stays readable.
+func requestHasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	if r.ContentLength >= 0 {
+		return r.ContentLength > 0
+	}
+
+	var b [1]byte
+	n, _ := io.ReadFull(r.Body, b[:])
+	if n == 0 {
+		return false
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b[:n]), r.Body), r.Body}
+	return true
+}
+
+// validate checks the JSON value val against schema, nested depth schemas
+// deep, appending a field error for every violation.
+func (v *openAPIValidator) validate(schema any, val any, pointer string, depth int, fields []FieldError) []FieldError {
+	s, _ := v.resolve(schema).(map[string]any)
+	if s == nil {
+		return fields
+	}
+	if depth++; depth > maxSchemaDepth {
+		return append(fields, FieldError{Pointer: pointer, Message: "is nested too deeply"})
+	}
+
+	if val == nil && s["nullable"] == true {
+		return fields
+	}
+	if types := schemaTypes(s); len(types) > 0 && !matchesType(types, val) {
+		return append(fields, FieldError{Pointer: pointer, Message: "must be " + strings.Join(types, " or ")})
+	}
+	if enum, ok := s["enum"].([]any); ok && !containsValue(enum, val) {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be one of the allowed values"})
+	}
+	if c, ok := s["const"]; ok && !equalValues(c, val) {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be the allowed value"})
+	}
+
+	switch x := val.(type) {
+	case string:
+		fields = v.validateString(s, x, pointer, fields)
+	case json.Number:
+		fields = validateNumber(s, x, pointer, fields)
+	case []any:
+		if n, ok := schemaNumber(s["minItems"]); ok && float64(len(x)) < n {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must have at least " + formatNumber(n) + " items"})
+		}
+		if n, ok := schemaNumber(s["maxItems"]); ok && float64(len(x)) > n {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must have at most " + formatNumber(n) + " items"})
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range x {
+				fields = v.validate(items, item, pointer+"/"+strconv.Itoa(i), depth, fields)
+			}
+		}
+	case map[string]any:
+		fields = v.validateObject(s, x, pointer, depth, fields)
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			fields = v.validate(sub, val, pointer, depth, fields)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(v.validate(sub, val, pointer, depth, nil)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must match a schema of anyOf"})
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if len(v.validate(sub, val, pointer, depth, nil)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must match exactly one schema of oneOf"})
+		}
+	}
+	return fields
+}
+
+func (v *openAPIValidator) validateString(s map[string]any, x string, pointer string, fields []FieldError) []FieldError {
+	n := float64(utf8.RuneCountInString(x))
+	if min, ok := schemaNumber(s["minLength"]); ok && n < min {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be at least " + formatNumber(min) + " characters"})
+	}
+	if max, ok := schemaNumber(s["maxLength"]); ok && n > max {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be at most " + formatNumber(max) + " characters"})
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re := v.patterns[pattern]; re != nil && !re.MatchString(x) {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must match pattern " + pattern})
+		}
+	}
+	if format, ok := s["format"].(string); ok && !matchesFormat(format, x) {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be a valid " + format})
+	}
+	return fields
+}
+
+func validateNumber(s map[string]any, x json.Number, pointer string, fields []FieldError) []FieldError {
+	f, _ := x.Float64()
+	if min, ok := schemaNumber(s["minimum"]); ok {
+		if s["exclusiveMinimum"] == true && f <= min {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must be greater than " + formatNumber(min)})
+		} else if f < min {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must be at least " + formatNumber(min)})
+		}
+	}
+	if max, ok := schemaNumber(s["maximum"]); ok {
+		if s["exclusiveMaximum"] == true && f >= max {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must be less than " + formatNumber(max)})
+		} else if f > max {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must be at most " + formatNumber(max)})
+		}
+	}
+	// OpenAPI 3.1 gives exclusive bounds as numbers
+	if min, ok := schemaNumber(s["exclusiveMinimum"]); ok && f <= min {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be greater than " + formatNumber(min)})
+	}
+	if max, ok := schemaNumber(s["exclusiveMaximum"]); ok && f >= max {
+		fields = append(fields, FieldError{Pointer: pointer, Message: "must be less than " + formatNumber(max)})
+	}
+	if m, ok := schemaNumber(s["multipleOf"]); ok && m > 0 {
+		if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			fields = append(fields, FieldError{Pointer: pointer, Message: "must be a multiple of " + formatNumber(m)})
+		}
+	}
+	return fields
+}
+
+func (v *openAPIValidator) validateObject(s map[string]any, x map[string]any, pointer string, depth int, fields []FieldError) []FieldError {
+	required, _ := s["required"].([]any)
+	for _, name := range required {
+		if name, ok := name.(string); ok {
+			if _, ok := x[name]; !ok {
+				fields = append(fields, FieldError{Pointer: pointer + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	for name, value := range x {
+		p := pointer + "/" + escapePointer(name)
+		if prop, ok := props[name]; ok {
+			fields = v.validate(prop, value, p, depth, fields)
+			continue
+		}
+		switch {
+		case !hasAdditional:
+		case additional == false:
+			fields = append(fields, FieldError{Pointer: p, Message: "is not allowed"})
+		default:
+			fields = v.validate(additional, value, p, depth, fields)
+		}
+	}
+	return fields
+}
+
+// compilePatterns compiles the pattern of every schema found below x,
+// skipping examples, which are values rather than schemas.
+func (v *openAPIValidator) compilePatterns(x any) error {
+	switch x := x.(type) {
+	case map[string]any:
+		for key, val := range x {
+			switch key {
+			case "example", "examples":
+				continue
+			case "pattern":
+				if pattern, ok := val.(string); ok {
+					if _, ok := v.patterns[pattern]; ok {
+						continue
+					}
+					re, err := regexp.Compile(pattern)
+					if err != nil {
+						return fmt.Errorf("OpenAPIValidator: pattern %q: %w", pattern, err)
+					}
+					v.patterns[pattern] = re
+					continue
+				}
+			}
+			if err := v.compilePatterns(val); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, val := range x {
+			if err := v.compilePatterns(val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes returns the types a schema allows; OpenAPI 3.1 allows a list.
+func schemaTypes(s map[string]any) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, x := range t {
+			if x, ok := x.(string); ok {
+				types = append(types, x)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaHasType(s map[string]any, typ string) bool {
+	for _, t := range schemaTypes(s) {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(types []string, val any) bool {
+	for _, t := range types {
+		switch x := val.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if f, err := x.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		return isDateParam(s)
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uuid":
+		return isUUIDParam(s)
+	}
+	return true
+}
+
+func schemaNumber(x any) (float64, bool) {
+	n, ok := x.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func containsValue(list []any, val any) bool {
+	for _, x := range list {
+		if equalValues(x, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares decoded JSON values, numbers by value.
+func equalValues(a, b any) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, _ := x.Float64()
+		fy, _ := y.Float64()
+		return fx == fy
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// escapePointer escapes a JSON pointer token.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validatorSpec = `{
+  "openapi": "3.1.0",
+  "info": {"title": "Test", "version": "1"},
+  "paths": {
+    "/users/{id}": {
+      "parameters": [
+        {"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}},
+        {"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string"}}
+      ],
+      "get": {
+        "parameters": [
+          {"name": "fields", "in": "query", "explode": false, "schema": {"type": "array", "items": {"enum": ["id", "name"]}}}
+        ]
+      },
+      "put": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["name", "email"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 2},
+          "email": {"type": "string", "format": "email"},
+          "nickname": {"type": ["string", "null"]},
+          "roles": {"type": "array", "items": {"type": "string", "enum": ["admin", "user"]}}
+        }
+      }
+    }
+  }
+}`
+
+func TestOpenAPIValidator(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(file, []byte(validatorSpec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := OpenAPIValidator(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := New()
+	app.Use(validator)
+	var validateErr error
+	app.SetErrorHandler(func(c *Ctx, err error) error {
+		validateErr = err
+		return err
+	})
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+	app.Put("/users/:id", func(c *Ctx) (any, error) {
+		var user map[string]any
+		if err := c.TryParseBody(&user); err != nil {
+			return nil, err
+		}
+		return user["name"], nil
+	})
+	app.Get("/health", func(c *Ctx) (any, error) { return nil, nil })
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		tenant   bool
+		code     int
+		pointers []string
+	}{
+		{name: "valid", method: http.MethodGet, path: "/users/1?fields=id,name&page=2", tenant: true, code: http.StatusNoContent},
+		{name: "undescribed", method: http.MethodGet, path: "/health?page=x", code: http.StatusNoContent},
+		{name: "params", method: http.MethodGet, path: "/users/0?fields=id,email&page=0&limit=x", code: http.StatusBadRequest,
+			pointers: []string{"/header/x-tenant", "/path/id", "/query/fields/1", "/query/limit", "/query/page"}},
+		{name: "valid body", method: http.MethodPut, path: "/users/1", tenant: true, body: `{"name":"Ann","email":"ann@example.com","nickname":null}`, code: http.StatusOK},
+		{name: "missing body", method: http.MethodPut, path: "/users/1", tenant: true, code: http.StatusBadRequest, pointers: []string{"/body"}},
+		{name: "invalid body", method: http.MethodPut, path: "/users/1", tenant: true, body: `{"name":"A","email":"nope","roles":["root"],"extra/x":1}`, code: http.StatusBadRequest,
+			pointers: []string{"/body/email", "/body/extra~1x", "/body/name", "/body/roles/0"}},
+		{name: "malformed body", method: http.MethodPut, path: "/users/1", tenant: true, body: `{`, code: http.StatusBadRequest, pointers: []string{"/body"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validateErr = nil
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Accept", "application/json")
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.tenant {
+				req.Header.Set("X-Tenant", "acme")
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+			if tt.pointers == nil {
+				return
+			}
+			if !errors.Is(validateErr, ErrInvalid) {
+				t.Fatalf("expected ErrInvalid, got %v", validateErr)
+			}
+
+			var body ErrorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected a structured error, got %q", rec.Body.String())
+			}
+			var pointers []string
+			for _, f := range body.Errors {
+				pointers = append(pointers, f.Pointer)
+			}
+			if body.Message != "INVALID" || !reflect.DeepEqual(pointers, tt.pointers) {
+				t.Fatalf("expected pointers %v, got %+v", tt.pointers, body)
+			}
+		})
+	}
+
+	for _, spec := range []string{`{"swagger":"2.0"}`, `{"openapi":"3.0.3","paths":{"/a:b":{}}}`, `{`,
+		`{"openapi":"3.0.3","components":{"schemas":{"Code":{"type":"string","pattern":"["}}}}`} {
+		if _, err := newOpenAPIValidator([]byte(spec)); err == nil {
+			t.Fatalf("%s: expected an error", spec)
+		}
+	}
+}
+
+const validatorLimitsSpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/codes": {
+      "post": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Code"}}}
+        }
+      }
+    },
+    "/loops": {
+      "post": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Loop"}}}
+        }
+      }
+    },
+    "/uploads": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"type": "object"}}, "image/*": {}}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Code": {
+        "type": "object",
+        "properties": {"code": {"type": "string", "pattern": "^[A-Z]{3}$", "example": {"pattern": "("}}}
+      },
+      "Loop": {"allOf": [{"$ref": "#/components/schemas/Loop"}]}
+    }
+  }
+}`
+
+func TestOpenAPIValidatorLimits(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(file, []byte(validatorLimitsSpec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := OpenAPIValidatorWithOptions(file, OpenAPIValidatorOptions{MaxBodyBytes: 32})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := New()
+	app.Use(validator)
+	app.Post("/codes", func(c *Ctx) (any, error) { return nil, nil })
+	app.Post("/loops", func(c *Ctx) (any, error) { return nil, nil })
+	app.Post("/uploads", func(c *Ctx) (any, error) {
+		data, err := io.ReadAll(c.Request().Body)
+		return len(data), err
+	})
+
+	upload := strings.Repeat("x", 64)
+	tests := []struct {
+		name    string
+		path    string
+		ctype   string
+		body    string
+		chunked bool
+		code    int
+		want    string
+	}{
+		{name: "pattern", path: "/codes", body: `{"code":"ABC"}`, code: http.StatusNoContent},
+		{name: "pattern mismatch", path: "/codes", body: `{"code":"abc"}`, code: http.StatusBadRequest},
+		{name: "too large", path: "/codes", body: `{"code":"ABC","padding":"` + strings.Repeat("x", 32) + `"}`, code: http.StatusRequestEntityTooLarge},
+		{name: "self reference", path: "/loops", body: `{}`, code: http.StatusBadRequest},
+		{name: "unread upload", path: "/uploads", ctype: "image/png", body: upload, code: http.StatusOK, want: "64\n"},
+		{name: "unread chunked upload", path: "/uploads", ctype: "image/png", body: upload, chunked: true, code: http.StatusOK, want: "64\n"},
+		{name: "missing upload", path: "/uploads", ctype: "image/png", chunked: true, code: http.StatusBadRequest},
+		{name: "undocumented type", path: "/uploads", ctype: "text/plain", body: upload, code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.ctype == "" {
+				tt.ctype = "application/json"
+			}
+			req.Header.Set("Content-Type", tt.ctype)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+			if tt.want != "" && rec.Body.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
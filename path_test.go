@@ -0,0 +1,28 @@
+package web
+
+import "testing"
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: "/"},
+		{path: "/", want: "/"},
+		{path: "/abc", want: "/abc"},
+		{path: "abc/def", want: "/abc/def"},
+		{path: "/abc//def", want: "/abc/def"},
+		{path: "/abc/./def", want: "/abc/def"},
+		{path: "/abc/../def", want: "/def"},
+		{path: "/../abc", want: "/abc"},
+		{path: "/abc/def/", want: "/abc/def/"},
+		{path: "/abc/def/..", want: "/abc"},
+		{path: "/abc/.", want: "/abc/"},
+	}
+
+	for _, tt := range tests {
+		if got := cleanPath(tt.path); got != tt.want {
+			t.Fatalf("cleanPath(%q): expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
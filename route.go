@@ -0,0 +1,162 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route is a registered route. It is returned by the registration methods so
+// that the route can be named after it has been added.
+type Route struct {
+	app        *Application
+	group      *RouteGroup
+	host       string
+	version    string
+	name       string
+	path       string
+	params     []string
+	handler    Next
+	middleware Chain
+	chain      Chain
+	options    []RouteOption
+	meta       map[string]any
+}
+
+// Name names the route for reverse URL building with Application.URL.
+// It panics if the name is already used by a route with a different path,
+// host or version.
+func (r *Route) Name(name string) *Route {
+	if name == "" {
+		panic("route name must not be empty in path '" + r.path + "'")
+	}
+
+	app := r.app
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if prev, ok := app.named[name]; ok && (prev.path != r.path || prev.host != r.host || prev.version != r.version) {
+		panic("route name '" + name + "' in " + r.where() + " is already used by " + prev.where())
+	}
+
+	if app.named == nil {
+		app.named = make(map[string]*Route)
+	}
+
+	r.name = name
+	app.named[name] = r
+	return r
+}
+
+// where describes the route for panic messages, e.g.
+// "path '/users/:id' on host 'api.example.com'".
+func (r *Route) where() string {
+	s := "path '" + r.path + "'"
+	if r.host != "" {
+		s += " on host '" + r.host + "'"
+	}
+	if r.version != "" {
+		s += " in version '" + r.version + "'"
+	}
+	return s
+}
+
+// URL builds the path of the route registered under name. Params are given as
+// key/value pairs, e.g. app.URL("user.show", "id", "42"). Every param of the
+// route must be given exactly once; missing and extra params are errors.
+// Optional params may be left out, dropping their segment.
+func (app *Application) URL(name string, pairs ...string) (string, error) {
+	app.mu.Lock()
+	r, ok := app.named[name]
+	app.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("URL: no route named %q", name)
+	}
+
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("URL: odd number of params for route %q", name)
+	}
+
+	used := 0
+	omitted := false
+	path := r.path
+
+	var sb strings.Builder
+
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			sb.WriteString(path)
+			break
+		}
+
+		static := path[:i]
+		path = path[i+len(wildcard):]
+
+		key := strings.TrimSuffix(wildcard[1:], "?")
+		if wildcard[0] == ':' {
+			key, _ = splitConstraint(wildcard)
+		}
+		val, found := "", false
+		for j := 0; j < len(pairs); j += 2 {
+			if pairs[j] == key {
+				if found {
+					return "", fmt.Errorf("URL: param %q given twice for route %q", key, name)
+				}
+				val, found = pairs[j+1], true
+			}
+		}
+
+		// An omitted optional param drops its segment. Being trailing, the
+		// params after it must be omitted too.
+		if !found && wildcard[len(wildcard)-1] == '?' {
+			sb.WriteString(static[:len(static)-1])
+			if sb.Len() == 0 {
+				sb.WriteByte('/')
+			}
+			omitted = true
+			continue
+		}
+		if !found {
+			return "", fmt.Errorf("URL: missing param %q for route %q", key, name)
+		}
+		if omitted {
+			return "", fmt.Errorf("URL: param %q given after an omitted optional param for route %q", key, name)
+		}
+		used++
+
+		sb.WriteString(static)
+
+		if wildcard[0] == '*' {
+			// The catch-all value keeps its slashes, the leading one is
+			// already part of the pattern.
+			u := url.URL{Path: strings.TrimPrefix(val, "/")}
+			sb.WriteString(u.EscapedPath())
+			continue
+		}
+
+		if val == "" {
+			return "", fmt.Errorf("URL: empty param %q for route %q", key, name)
+		}
+		sb.WriteString(url.PathEscape(val))
+	}
+
+	if used*2 != len(pairs) {
+		for j := 0; j < len(pairs); j += 2 {
+			if !containsString(r.params, pairs[j]) {
+				return "", fmt.Errorf("URL: unknown param %q for route %q", pairs[j], name)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
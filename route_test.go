@@ -0,0 +1,174 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestURLBuildsNamedRoutes(t *testing.T) {
+	app := New()
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user.show")
+	api := app.Group("/api")
+	api.Get("/files/:owner/*path", func(c *Ctx) (any, error) { return nil, nil }).Name("file.get")
+	app.Get("/health", func(c *Ctx) (any, error) { return nil, nil }).Name("health")
+	app.Get("/reports/:year/:month?", func(c *Ctx) (any, error) { return nil, nil }).Name("report")
+	app.Get("/files/:name.:ext", func(c *Ctx) (any, error) { return nil, nil }).Name("file.raw")
+
+	tests := []struct {
+		name  string
+		pairs []string
+		want  string
+	}{
+		{name: "user.show", pairs: []string{"id", "42"}, want: "/users/42"},
+		{name: "user.show", pairs: []string{"id", "a b/c"}, want: "/users/a%20b%2Fc"},
+		{name: "file.get", pairs: []string{"owner", "bob", "path", "/docs/a b.txt"}, want: "/api/files/bob/docs/a%20b.txt"},
+		{name: "file.get", pairs: []string{"path", "docs/readme", "owner", "bob"}, want: "/api/files/bob/docs/readme"},
+		{name: "health", want: "/health"},
+		{name: "report", pairs: []string{"year", "2024"}, want: "/reports/2024"},
+		{name: "report", pairs: []string{"year", "2024", "month", "05"}, want: "/reports/2024/05"},
+		{name: "file.raw", pairs: []string{"name", "a", "ext", "txt"}, want: "/files/a.txt"},
+	}
+
+	for _, tt := range tests {
+		got, err := app.URL(tt.name, tt.pairs...)
+		if err != nil {
+			t.Fatalf("%s %v: unexpected error: %v", tt.name, tt.pairs, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s %v: expected %q, got %q", tt.name, tt.pairs, tt.want, got)
+		}
+	}
+
+	if got := app.Routes()[0].Name; got != "file.get" {
+		t.Fatalf("expected route info to carry name, got %q", got)
+	}
+}
+
+func TestURLRejectsMissingAndExtraParams(t *testing.T) {
+	app := New()
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user.show")
+
+	for _, pairs := range [][]string{
+		nil,
+		{"id"},
+		{"id", ""},
+		{"id", "1", "extra", "2"},
+		{"id", "1", "id", "2"},
+	} {
+		if got, err := app.URL("user.show", pairs...); err == nil {
+			t.Fatalf("%v: expected error, got %q", pairs, got)
+		}
+	}
+
+	if _, err := app.URL("missing"); err == nil {
+		t.Fatalf("expected error for unknown route name")
+	}
+}
+
+func TestRouteNameConflictPanics(t *testing.T) {
+	app := New()
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user")
+	app.Handle(http.MethodPut, "/users/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for route name reused by another path")
+		}
+	}()
+	app.Get("/accounts/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user")
+}
+
+func TestRouteNameConflictAcrossHostsAndVersionsPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(app *Application) *Route
+	}{
+		{"host", func(app *Application) *Route {
+			return app.Host("api.example.com").Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+		}},
+		{"version", func(app *Application) *Route {
+			return app.Version("2").Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New()
+			app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil }).Name("user")
+			r := tt.register(app)
+
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for route name reused by another %s", tt.name)
+				}
+			}()
+			r.Name("user")
+		})
+	}
+}
+
+func TestCtxRouteReportsMatchedRoute(t *testing.T) {
+	app := New()
+	var logged MatchedRoute
+	app.Pre(AccessLog(func(c *Ctx, status int, d time.Duration, err error) {
+		logged = c.Route()
+	}))
+
+	var got MatchedRoute
+	record := func(c *Ctx) (any, error) {
+		got = c.Route()
+		return nil, nil
+	}
+	app.With(Meta("service", "accounts"), Meta("tag", "root"))
+	users := app.Group("/users").With(Meta("tag", "users"), Meta("auth", "user"))
+	users.Get("/:id", record).With(Meta("auth", "admin")).Name("user.show")
+	app.Any("/any/*path", record)
+
+	tests := []struct {
+		method string
+		path   string
+		want   MatchedRoute
+	}{
+		{method: http.MethodGet, path: "/users/42", want: MatchedRoute{Method: http.MethodGet, Pattern: "/users/:id", Name: "user.show", Meta: map[string]any{"service": "accounts", "tag": "users", "auth": "admin"}}},
+		{method: http.MethodHead, path: "/users/42", want: MatchedRoute{Method: http.MethodGet, Pattern: "/users/:id", Name: "user.show", Meta: map[string]any{"service": "accounts", "tag": "users", "auth": "admin"}}},
+		{method: http.MethodPost, path: "/any/x", want: MatchedRoute{Method: "*", Pattern: "/any/*path", Meta: map[string]any{"service": "accounts", "tag": "root"}}},
+	}
+
+	for _, tt := range tests {
+		got, logged = MatchedRoute{}, MatchedRoute{}
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s %s: expected %+v, got %+v", tt.method, tt.path, tt.want, got)
+		}
+		if !reflect.DeepEqual(logged, tt.want) {
+			t.Fatalf("%s %s: expected access log to see %+v, got %+v", tt.method, tt.path, tt.want, logged)
+		}
+	}
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if logged.Pattern != "" {
+		t.Fatalf("expected no route for a 404, got %+v", logged)
+	}
+
+	for _, r := range app.Routes() {
+		if r.Path == "/users/:id" && (r.Middleware != 0 || r.Meta["tag"] != "users") {
+			t.Fatalf("expected Meta to be listed but not to wrap the route, got %+v", r)
+		}
+	}
+
+	// Options added later reach earlier routes through Build, like Use
+	users.With(Meta("auth", "staff"))
+	app.With(Meta("team", "core"))
+	app.Build()
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/any/x", nil))
+	if want := map[string]any{"service": "accounts", "tag": "root", "team": "core"}; !reflect.DeepEqual(got.Meta, want) {
+		t.Fatalf("expected rebuilt metadata %v, got %v", want, got.Meta)
+	}
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if got.Meta["auth"] != "admin" || got.Meta["team"] != "core" {
+		t.Fatalf("expected route options to win after Build, got %v", got.Meta)
+	}
+}
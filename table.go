@@ -0,0 +1,275 @@
+package web
+
+// routeTable holds every route of an application. Requests read the current
+// table without locking. Changes are made to a copy under the application's
+// lock, copying only the nodes on the way to the changed routes, and the copy
+// then replaces the table atomically.
+type routeTable struct {
+	router
+	hosts         map[string]*hostRouter
+	wildcardHosts []*hostRouter
+	versions      map[string]*router
+}
+
+func (t *routeTable) clone() *routeTable {
+	c := &routeTable{
+		router:        t.router.clone(),
+		wildcardHosts: append([]*hostRouter(nil), t.wildcardHosts...),
+	}
+	if t.hosts != nil {
+		c.hosts = make(map[string]*hostRouter, len(t.hosts))
+		for name, h := range t.hosts {
+			c.hosts[name] = h
+		}
+	}
+	if t.versions != nil {
+		c.versions = make(map[string]*router, len(t.versions))
+		for v, rt := range t.versions {
+			c.versions[v] = rt
+		}
+	}
+	return c
+}
+
+// scope returns the router of host or of the API version, or of the
+// application itself if both are empty, ready to be changed. Routers shared
+// with the previous table are copied first.
+func (t *routeTable) scope(host, version string) *router {
+	if version != "" {
+		rt := t.versions[version]
+		if rt == nil {
+			return nil
+		}
+		c := rt.clone()
+		t.versions[version] = &c
+		return &c
+	}
+	if host == "" {
+		return &t.router
+	}
+
+	h := t.host(host)
+	if h == nil {
+		return nil
+	}
+
+	c := &hostRouter{router: h.router.clone(), labels: h.labels}
+	if t.hosts[host] == h {
+		t.hosts[host] = c
+	} else {
+		for i := range t.wildcardHosts {
+			if t.wildcardHosts[i] == h {
+				t.wildcardHosts[i] = c
+			}
+		}
+	}
+	return &c.router
+}
+
+// host returns the host router registered for the host pattern.
+func (t *routeTable) host(pattern string) *hostRouter {
+	if h := t.hosts[pattern]; h != nil {
+		return h
+	}
+	for _, h := range t.wildcardHosts {
+		if h.host == pattern {
+			return h
+		}
+	}
+	return nil
+}
+
+// eachRouter calls fn for the application router and every host and
+// version router.
+func (t *routeTable) eachRouter(fn func(rt *router)) {
+	fn(&t.router)
+	for _, h := range t.hosts {
+		fn(&h.router)
+	}
+	for _, h := range t.wildcardHosts {
+		fn(&h.router)
+	}
+	for _, rt := range t.versions {
+		fn(rt)
+	}
+}
+
+// eachScope calls fn for every router of the table, ready to be changed
+// like the routers returned by scope.
+func (t *routeTable) eachScope(fn func(rt *router)) {
+	fn(&t.router)
+	for _, h := range t.hosts {
+		fn(t.scope(h.host, ""))
+	}
+	for _, h := range t.wildcardHosts {
+		fn(t.scope(h.host, ""))
+	}
+	for v := range t.versions {
+		fn(t.scope("", v))
+	}
+}
+
+func (rt router) clone() router {
+	if rt.trees != nil {
+		trees := make(map[string]*node, len(rt.trees))
+		for method, root := range rt.trees {
+			trees[method] = root
+		}
+		rt.trees = trees
+	}
+	return rt
+}
+
+// tree returns the root of the method tree, or of the Any tree for
+// methodAny.
+func (rt *router) tree(method string) *node {
+	if method == methodAny {
+		return rt.anyRoot
+	}
+	return rt.trees[method]
+}
+
+// eachTree calls fn for every method tree and the Any tree.
+func (rt *router) eachTree(fn func(method string, root *node)) {
+	for method, root := range rt.trees {
+		fn(method, root)
+	}
+	if rt.anyRoot != nil {
+		fn(methodAny, rt.anyRoot)
+	}
+}
+
+// contains reports whether the route r is still registered for any method.
+func (rt *router) contains(r *Route) bool {
+	found := false
+	rt.eachTree(func(_ string, root *node) {
+		root.eachLeaf(nil, func(n *node, _ []*node) {
+			found = found || n.route == r
+		})
+	})
+	return found
+}
+
+// insert adds the route r for method, once for every expansion of its
+// optional params.
+func (rt *router) insert(method string, r *Route, next Next) {
+	root := rt.tree(method)
+	if root == nil {
+		root = new(node)
+	} else {
+		root = root.clone()
+	}
+
+	for _, path := range expandOptional(r.path) {
+		root.addRoute(path, next).route = r
+	}
+
+	rt.setRoot(method, root)
+}
+
+// remove removes the route registered for method and the pattern path and
+// returns it, or nil if there is none.
+func (rt *router) remove(method string, path string) *Route {
+	root := rt.tree(method)
+	if root == nil {
+		return nil
+	}
+
+	var removed *Route
+	for _, p := range expandOptional(path) {
+		var r *Route
+		if root, r = root.removeRoute(p); r != nil {
+			removed = r
+		}
+		if root == nil {
+			break
+		}
+	}
+
+	if removed != nil {
+		rt.setRoot(method, root)
+	}
+	return removed
+}
+
+func (rt *router) setRoot(method string, root *node) {
+	if method == methodAny {
+		rt.anyRoot = root
+		return
+	}
+
+	if rt.trees == nil {
+		rt.trees = make(map[string]*node)
+	}
+
+	idx := methodRootIndex(method)
+	if root == nil {
+		delete(rt.trees, method)
+		if idx >= 0 {
+			rt.methodRoots[idx] = nil
+		}
+	} else {
+		rt.trees[method] = root
+		if idx >= 0 {
+			rt.methodRoots[idx] = root
+		}
+	}
+
+	rt.globalAllowed = rt.allowedSlow("*", "")
+}
+
+// update applies fn to a copy of the route table and publishes the copy.
+// Registration panics leave the current table untouched.
+func (app *Application) update(fn func(t *routeTable)) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	t := app.table.Load().clone()
+	fn(t)
+	app.table.Store(t)
+}
+
+// Remove removes the route registered for method and path, the pattern it
+// was registered with, and reports whether there was one. Routes registered
+// with Any are removed with the method "*". It is safe to call
+// while serving; requests already routed finish with the removed route.
+func (app *Application) Remove(method string, path string) bool {
+	return app.Group("").Remove(method, path)
+}
+
+// Replace registers a route like Handle, replacing the route registered for
+// method and path if there is one. Requests see either route, never none.
+func (app *Application) Replace(method string, path string, next Next, middleware ...Middleware) *Route {
+	return app.Group("").Replace(method, path, next, middleware...)
+}
+
+// Remove removes the route registered on the group for method and path.
+func (g *RouteGroup) Remove(method string, path string) bool {
+	path = joinPaths(g.prefix, path)
+
+	removed := false
+	g.app.update(func(t *routeTable) {
+		if rt := t.scope(g.host, g.version); rt != nil {
+			if r := rt.remove(method, path); r != nil {
+				if !rt.contains(r) {
+					g.app.unname(r)
+				}
+				removed = true
+			}
+		}
+	})
+	return removed
+}
+
+// Replace registers a route on the group, replacing the route registered for
+// method and path if there is one.
+func (g *RouteGroup) Replace(method string, path string, next Next, middleware ...Middleware) *Route {
+	return g.app.addRoute(g, []string{method}, joinPaths(g.prefix, path), next, middleware, true)
+}
+
+// unname drops the name of a removed route.
+func (app *Application) unname(r *Route) {
+	if r.name != "" && app.named[r.name] == r {
+		delete(app.named, r.name)
+	}
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func serveStatus(app *Application, method, path string) int {
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec.Code
+}
+
+func TestRemoveRoute(t *testing.T) {
+	app := New()
+	ok := func(c *Ctx) (any, error) { return "ok", nil }
+	app.Get("/users/:id", ok).Name("user.show")
+	app.Get("/users/new", ok)
+	app.Post("/users/:id", ok)
+	app.Get("/reports/:year/:month?", ok)
+	app.Host("api.example.com").Get("/ping", ok)
+
+	if !app.Remove(http.MethodGet, "/users/:id") {
+		t.Fatalf("expected route to be removed")
+	}
+	if app.Remove(http.MethodGet, "/users/:id") {
+		t.Fatalf("expected second removal to report no route")
+	}
+	if code := serveStatus(app, http.MethodGet, "/users/42"); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 after removal, got %d", code)
+	}
+	if code := serveStatus(app, http.MethodGet, "/users/new"); code != http.StatusOK {
+		t.Fatalf("expected sibling route to survive, got %d", code)
+	}
+	if _, err := app.URL("user.show", "id", "1"); err == nil {
+		t.Fatalf("expected the name of a removed route to be released")
+	}
+
+	// The pruned param node no longer conflicts with a new param name
+	app.Get("/users/:name", ok)
+	if code := serveStatus(app, http.MethodGet, "/users/bob"); code != http.StatusOK {
+		t.Fatalf("expected re-registered route, got %d", code)
+	}
+
+	if !app.Remove(http.MethodGet, "/reports/:year/:month?") {
+		t.Fatalf("expected optional route to be removed")
+	}
+	for _, path := range []string{"/reports/2024", "/reports/2024/05"} {
+		if code := serveStatus(app, http.MethodGet, path); code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 after removal, got %d", path, code)
+		}
+	}
+
+	if !app.Host("api.example.com").Remove(http.MethodGet, "/ping") {
+		t.Fatalf("expected host route to be removed")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Host = "api.example.com"
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for removed host route, got %d", rec.Code)
+	}
+}
+
+func TestReplaceRoute(t *testing.T) {
+	app := New()
+	app.Get("/feature", func(c *Ctx) (any, error) { return "v1", nil }).Name("feature")
+	app.Replace(http.MethodGet, "/feature", func(c *Ctx) (any, error) { return "v2", nil })
+	app.Replace(http.MethodGet, "/new", func(c *Ctx) (any, error) { return "new", nil })
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feature", nil))
+	if got := rec.Body.String(); got != "\"v2\"\n" {
+		t.Fatalf("expected replaced handler, got %q", got)
+	}
+	if got, err := app.URL("feature"); err != nil || got != "/feature" {
+		t.Fatalf("expected the replacement to keep the name, got %q, %v", got, err)
+	}
+	if code := serveStatus(app, http.MethodGet, "/new"); code != http.StatusOK {
+		t.Fatalf("expected Replace to add a missing route, got %d", code)
+	}
+}
+
+func TestRouteChangesWhileServing(t *testing.T) {
+	app := New()
+	app.Get("/static", func(c *Ctx) (any, error) { return nil, nil })
+	app.Get("/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if code := serveStatus(app, http.MethodGet, "/static"); code != http.StatusNoContent {
+					t.Errorf("expected stable route to answer 204, got %d", code)
+					return
+				}
+				serveStatus(app, http.MethodGet, "/plugins/3/items/7")
+				serveStatus(app, http.MethodGet, "/users/42")
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		path := "/plugins/" + strconv.Itoa(i%8) + "/items/:item"
+		app.Get(path, func(c *Ctx) (any, error) { return nil, nil })
+		app.Replace(http.MethodGet, "/users/:id", func(c *Ctx) (any, error) { return nil, nil })
+		app.Remove(http.MethodGet, path)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -25,7 +25,11 @@ func longestCommonPrefix(a, b string) int {
 	return i
 }
 
-// Search for a wildcard segment and check the name for invalid characters.
+// Search for a wildcard and check the name for invalid characters.
+// A param name is made of letters, digits and '_', optionally followed by a
+// constraint in angle brackets and a '?' marking the param optional, so one
+// segment may hold several params separated by static text, e.g. :name.:ext.
+// A catch-all spans the rest of the segment.
 // Returns -1 as index, if no wildcard was found.
 func findWildcard(path string) (wilcard string, i int, valid bool) {
 	// Find start
@@ -36,50 +40,141 @@ func findWildcard(path string) (wilcard string, i int, valid bool) {
 			continue
 		}
 
+		if c == '*' {
+			end := start + nextSlash(path[start:])
+			return path[start:end], start, strings.IndexAny(path[start+1:end], ":*") < 0
+		}
+
 		// Find end and check for invalid characters
 		valid = true
-		for end := start + 1; end < len(path); end++ {
-			c = path[end]
-			switch c {
-			case '/':
-				return path[start:end], start, valid
-			case ':', '*':
+		end := start + 1
+		for end < len(path) && isParamNameChar(path[end]) {
+			end++
+		}
+
+		if end < len(path) && path[end] == '<' {
+			// Skip the constraint up to its closing '>' within the
+			// segment, it may contain ':', '*' and nested brackets
+			depth := 0
+			for ; end < len(path) && path[end] != '/'; end++ {
+				if path[end] == '<' {
+					depth++
+				} else if path[end] == '>' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if depth == 0 {
+				end++
+			} else {
 				valid = false
 			}
 		}
-		return path[start:], start, valid
+
+		if end < len(path) && path[end] == '?' {
+			end++
+		}
+
+		// Params in one segment must be separated by static text
+		if end < len(path) && (path[end] == ':' || path[end] == '*' || path[end] == '?') {
+			valid = false
+		}
+
+		return path[start:end], start, valid
 	}
 	return "", -1, false
 }
 
+func isParamNameChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+// nextSlash returns the index of the first '/' in path, or len(path).
+func nextSlash(path string) int {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return i
+	}
+	return len(path)
+}
+
+// countParams returns the number of wildcards in path.
 func countParams(path string) uint16 {
-	var n uint
-	for i := 0; i < len(path); i++ {
-		switch path[i] {
-		case ':', '*':
-			n++
+	return uint16(len(paramNames(path)))
+}
+
+// expandOptional returns the paths a pattern with optional trailing params,
+// like /reports/:year/:month?, stands for, shortest first. Optional params
+// must fill whole segments at the end of the path.
+func expandOptional(path string) []string {
+	var paths []string
+	rest, built := path, ""
+	for {
+		wildcard, i, _ := findWildcard(rest)
+		if i < 0 {
+			break
+		}
+
+		end := i + len(wildcard)
+		if wildcard[len(wildcard)-1] != '?' {
+			if paths != nil {
+				panic("optional params must be trailing path segments in path '" + path + "'")
+			}
+			built += rest[:end]
+			rest = rest[end:]
+			continue
+		}
+
+		if i == 0 || rest[i-1] != '/' || (end < len(rest) && rest[end] != '/') ||
+			(paths != nil && i != 1) {
+			panic("optional params must be trailing path segments in path '" + path + "'")
 		}
+
+		prefix := built + rest[:i-1]
+		if prefix == "" {
+			prefix = "/"
+		}
+		paths = append(paths, prefix)
+		built += rest[:end-1]
+		rest = rest[end:]
+	}
+
+	if paths == nil {
+		return []string{path}
 	}
-	return uint16(n)
+	if rest != "" {
+		panic("optional params must be trailing path segments in path '" + path + "'")
+	}
+	return append(paths, built)
 }
 
 type nodeType uint8
 
 const (
 	static nodeType = iota // default
-	root
 	param
 	catchAll
 )
 
+// node is a node of a method tree. Static children are indexed by their
+// first byte, while param and catch-all children are kept apart, so that a
+// static segment like /users/new can live next to /users/:id. Lookups try
+// static children before params and params before the catch-all, and back
+// up to the next candidate when a branch ends without a callback.
 type node struct {
-	path      string
-	indices   string
-	wildChild bool
-	nType     nodeType
-	priority  uint32
-	children  []*node
-	next      Next
+	path     string
+	indices  string
+	nType    nodeType
+	priority uint32
+	children []*node
+	params   []*node
+	catchAll *node
+	next     Next
+	route    *Route
+
+	// key is set on param and catch-all nodes, constraint on param nodes
+	key        string
+	constraint *paramConstraint
 }
 
 // Increments priority of the given child and reorders if necessary
@@ -105,121 +200,42 @@ func (n *node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
-// addRoute adds a node with the given callback to the path.
-// Not concurrency-safe!
-func (n *node) addRoute(path string, callback Next) {
-	fullPath := path
-	n.priority++
+// clone returns a shallow copy of n with its own child lists.
+func (n *node) clone() *node {
+	c := *n
+	c.children = append([]*node(nil), n.children...)
+	c.params = append([]*node(nil), n.params...)
+	return &c
+}
 
-	// Empty tree
-	if n.path == "" && n.indices == "" {
-		n.insertChild(path, fullPath, callback)
-		n.nType = root
-		return
+// deepClone copies n and all its descendants.
+func (n *node) deepClone() *node {
+	c := n.clone()
+	for i, child := range c.children {
+		c.children[i] = child.deepClone()
 	}
-
-walk:
-	for {
-		// Find the longest common prefix.
-		// This also implies that the common prefix contains no ':' or '*'
-		// since the existing key can't contain those chars.
-		i := longestCommonPrefix(path, n.path)
-
-		// Split edge
-		if i < len(n.path) {
-			child := node{
-				path:      n.path[i:],
-				wildChild: n.wildChild,
-				nType:     static,
-				indices:   n.indices,
-				children:  n.children,
-				next:      n.next,
-				priority:  n.priority - 1,
-			}
-
-			n.children = []*node{&child}
-			// []byte for proper unicode char conversion, see #65
-			n.indices = string([]byte{n.path[i]})
-			n.path = path[:i]
-			n.next = nil
-			n.wildChild = false
-		}
-
-		// Make new node a child of this node
-		if i < len(path) {
-			path = path[i:]
-
-			if n.wildChild {
-				n = n.children[0]
-				n.priority++
-
-				// Check if the wildcard matches
-				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
-					// Adding a child to a catchAll is not possible
-					n.nType != catchAll &&
-					// Check for longer wildcard, e.g. :name and :names
-					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
-					continue walk
-				} else {
-					// Wildcard conflict
-					pathSeg := path
-					if n.nType != catchAll {
-						pathSeg = strings.SplitN(pathSeg, "/", 2)[0]
-					}
-					prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
-					panic("'" + pathSeg +
-						"' in new path '" + fullPath +
-						"' conflicts with existing wildcard '" + n.path +
-						"' in existing prefix '" + prefix +
-						"'")
-				}
-			}
-
-			idxc := path[0]
-
-			// '/' after param
-			if n.nType == param && idxc == '/' && len(n.children) == 1 {
-				n = n.children[0]
-				n.priority++
-				continue walk
-			}
-
-			// Check if a child with the next path byte exists
-			for i := 0; i < len(n.indices); i++ {
-				if n.indices[i] == idxc {
-					i = n.incrementChildPrio(i)
-					n = n.children[i]
-					continue walk
-				}
-			}
-
-			// Otherwise insert it
-			if idxc != ':' && idxc != '*' {
-				// []byte for proper unicode char conversion, see #65
-				n.indices += string([]byte{idxc})
-				child := &node{}
-				n.children = append(n.children, child)
-				n.incrementChildPrio(len(n.indices) - 1)
-				n = child
-			}
-			n.insertChild(path, fullPath, callback)
-			return
-		}
-
-		// Otherwise add callback to current node
-		if n.next != nil {
-			panic("a callback is already registered for path '" + fullPath + "'")
-		}
-		n.next = callback
-		return
+	for i, child := range c.params {
+		c.params[i] = child.deepClone()
+	}
+	if c.catchAll != nil {
+		c.catchAll = c.catchAll.deepClone()
 	}
+	return c
 }
 
-func (n *node) insertChild(path, fullPath string, callback Next) {
+// addRoute adds a node with the given callback to the path and returns the
+// leaf holding it. n is changed in place, its descendants on the way to the
+// leaf are copied first, so a tree shared with concurrent lookups stays
+// intact as long as n is a copy of its root.
+func (n *node) addRoute(path string, callback Next) *node {
+	fullPath := path
+	n.priority++
+
 	for {
 		// Find prefix until first wildcard
 		wildcard, i, valid := findWildcard(path)
 		if i < 0 { // No wilcard found
+			n = n.insertStatic(path)
 			break
 		}
 
@@ -230,235 +246,516 @@ func (n *node) insertChild(path, fullPath string, callback Next) {
 		}
 
 		// Check if the wildcard has a name
-		if len(wildcard) < 2 {
+		if len(wildcard) < 2 || wildcard[1] == '<' || wildcard[1] == '?' {
 			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
 		}
 
-		// Check if this node has existing children which would be
-		// unreachable if we insert the wildcard here
-		if len(n.children) > 0 {
-			panic("wildcard segment '" + wildcard +
-				"' conflicts with existing children in path '" + fullPath + "'")
-		}
-
 		// param
 		if wildcard[0] == ':' {
-			if i > 0 {
-				// Insert prefix before the current wildcard
-				n.path = path[:i]
-				path = path[i:]
-			}
-
-			n.wildChild = true
-			child := &node{
-				nType: param,
-				path:  wildcard,
+			if wildcard[len(wildcard)-1] == '?' {
+				panic("optional params must be expanded with expandOptional in path '" + fullPath + "'")
 			}
-			n.children = []*node{child}
-			n = child
-			n.priority++
-
-			// If the path doesn't end with the wildcard, then there
-			// will be another non-wildcard subpath starting with '/'
-			if len(wildcard) < len(path) {
-				path = path[len(wildcard):]
-				child := &node{
-					priority: 1,
-				}
-				n.children = []*node{child}
-				n = child
-				continue
-			}
-
-			// Otherwise we're done. Insert the callback in the new leaf
-			n.next = callback
-			return
+			n = n.insertStatic(path[:i]).insertParam(wildcard, fullPath)
+			path = path[i+len(wildcard):]
+			continue
 		}
 
 		// catchAll
-		if i+len(wildcard) != len(path) {
-			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
+		if strings.IndexByte(wildcard, '<') >= 0 {
+			panic("constraints are not allowed on catch-all routes in path '" + fullPath + "'")
 		}
 
-		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
-			panic("catch-all conflicts with existing callback for the path segment root in path '" + fullPath + "'")
+		if i+len(wildcard) != len(path) {
+			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
 		}
 
 		// Currently fixed width 1 for '/'
 		i--
-		if path[i] != '/' {
+		if i < 0 || path[i] != '/' {
 			panic("no / before catch-all in path '" + fullPath + "'")
 		}
 
-		n.path = path[:i]
+		n = n.insertStatic(path[:i]).insertCatchAll(path[i:], fullPath)
+		break
+	}
+
+	if n.next != nil {
+		panic("a callback is already registered for path '" + fullPath + "'")
+	}
+	n.next = callback
+	return n
+}
+
+// insertStatic inserts the static path below n, splitting edges where
+// needed, and returns the node the path ends in.
+func (n *node) insertStatic(path string) *node {
+	for len(path) > 0 {
+		idxc := path[0]
+
+		// Check if a child with the next path byte exists
+		pos := strings.IndexByte(n.indices, idxc)
+		if pos < 0 {
+			// []byte for proper unicode char conversion, see #65
+			n.indices += string([]byte{idxc})
+			child := &node{path: path}
+			n.children = append(n.children, child)
+			n.incrementChildPrio(len(n.indices) - 1)
+			return child
+		}
+
+		child := n.children[pos].clone()
+		n.children[pos] = child
+		n.incrementChildPrio(pos)
 
-		// First node: catchAll node with empty path
-		child := &node{
-			wildChild: true,
-			nType:     catchAll,
+		// Split edge
+		i := longestCommonPrefix(path, child.path)
+		if i < len(child.path) {
+			rest := *child
+			rest.path = child.path[i:]
+			rest.priority--
+			*child = node{
+				path:     child.path[:i],
+				indices:  string([]byte{rest.path[0]}),
+				children: []*node{&rest},
+				priority: child.priority,
+			}
 		}
-		n.children = []*node{child}
-		n.indices = string('/')
+
 		n = child
-		n.priority++
+		path = path[i:]
+	}
+	return n
+}
 
-		// Second node: node holding the variable
-		child = &node{
-			path:     path[i:],
-			nType:    catchAll,
-			next:     callback,
-			priority: 1,
+// insertParam returns the param child of n for the wildcard, adding it if
+// needed. Params with a constraint are tried before the one without.
+func (n *node) insertParam(wildcard, fullPath string) *node {
+	for i, child := range n.params {
+		if child.path == wildcard {
+			child = child.clone()
+			child.priority++
+			n.params[i] = child
+			return child
 		}
-		n.children = []*node{child}
+	}
 
-		return
+	key, constraint := splitConstraint(wildcard)
+	child := &node{
+		nType:    param,
+		path:     wildcard,
+		key:      key,
+		priority: 1,
+	}
+	if len(key) < len(wildcard)-1 {
+		child.constraint = newParamConstraint(constraint, fullPath)
+		n.params = append([]*node{child}, n.params...)
+		return child
 	}
 
-	// If no wildcard was found, simply insert the path and callback
-	n.path = path
-	n.next = callback
+	// Two unconstrained params would always shadow each other
+	for _, p := range n.params {
+		if p.constraint == nil {
+			panic("wildcard '" + wildcard + "' in new path '" + fullPath +
+				"' conflicts with existing wildcard '" + p.path + "'")
+		}
+	}
+	n.params = append(n.params, child)
+	return child
+}
+
+// insertCatchAll returns the catch-all child of n for the wildcard, which
+// includes the leading '/', adding it if needed.
+func (n *node) insertCatchAll(wildcard, fullPath string) *node {
+	if child := n.catchAll; child != nil {
+		if child.path != wildcard {
+			panic("catch-all '" + wildcard + "' in new path '" + fullPath +
+				"' conflicts with existing catch-all '" + child.path + "'")
+		}
+		child = child.clone()
+		child.priority++
+		n.catchAll = child
+		return child
+	}
+
+	n.catchAll = &node{
+		nType:    catchAll,
+		path:     wildcard,
+		key:      wildcard[2:],
+		priority: 1,
+	}
+	return n.catchAll
+}
+
+// removeRoute returns a copy of n without the route registered for the
+// pattern path, or nil if nothing is left of n, together with the removed
+// route. The nodes on the way are copied, n itself is left untouched and
+// returned as is if there is no such route.
+func (n *node) removeRoute(path string) (*node, *Route) {
+	c := n.clone()
+	var r *Route
+
+	switch wildcard, i, _ := findWildcard(path); {
+	case path == "":
+		if n.next == nil {
+			return n, nil
+		}
+		r = c.route
+		c.next, c.route = nil, nil
+
+	case i == 0:
+		// param
+		for j, child := range c.params {
+			if child.path != wildcard {
+				continue
+			}
+			var nc *node
+			if nc, r = child.removeRoute(path[len(wildcard):]); r == nil {
+				return n, nil
+			}
+			if nc == nil {
+				c.params = append(c.params[:j:j], c.params[j+1:]...)
+			} else {
+				c.params[j] = nc
+			}
+			break
+		}
+
+	case i == 1 && wildcard[0] == '*':
+		// catch-all, including its leading '/'
+		if c.catchAll == nil || c.catchAll.path != path {
+			return n, nil
+		}
+		if c.catchAll, r = c.catchAll.removeRoute(""); r == nil {
+			return n, nil
+		}
+
+	default:
+		pos := strings.IndexByte(c.indices, path[0])
+		if pos < 0 || !strings.HasPrefix(path, c.children[pos].path) {
+			return n, nil
+		}
+		var nc *node
+		if nc, r = c.children[pos].removeRoute(path[len(c.children[pos].path):]); r == nil {
+			return n, nil
+		}
+		if nc == nil {
+			c.indices = c.indices[:pos] + c.indices[pos+1:]
+			c.children = append(c.children[:pos:pos], c.children[pos+1:]...)
+		} else {
+			c.children[pos] = nc
+		}
+	}
+
+	if r == nil {
+		return n, nil
+	}
+	return c.compact(), r
+}
+
+// compact returns nil for a node left without callback and children, and
+// merges a static node without callback into its only static child.
+func (n *node) compact() *node {
+	if n.next != nil || n.catchAll != nil || len(n.params) > 0 {
+		return n
+	}
+	switch len(n.children) {
+	case 0:
+		return nil
+	case 1:
+		// The root keeps its empty path
+		if n.nType == static && n.path != "" {
+			merged := *n.children[0]
+			merged.path = n.path + merged.path
+			merged.priority = n.priority
+			return &merged
+		}
+	}
+	return n
+}
+
+// eachLeaf calls fn for every node holding a callback, together with the
+// constrained param nodes on the way to it.
+func (n *node) eachLeaf(constrained []*node, fn func(n *node, constrained []*node)) {
+	if n.constraint != nil {
+		constrained = append(constrained[:len(constrained):len(constrained)], n)
+	}
+	if n.next != nil {
+		fn(n, constrained)
+	}
+	for _, child := range n.children {
+		child.eachLeaf(constrained, fn)
+	}
+	for _, child := range n.params {
+		child.eachLeaf(constrained, fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.eachLeaf(constrained, fn)
+	}
+}
+
+// paramNames returns the names of all wildcards in path, in order.
+func paramNames(path string) []string {
+	var names []string
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(wildcard[1:], "?")
+		if wildcard[0] == ':' {
+			name, _ = splitConstraint(wildcard)
+		}
+		names = append(names, name)
+		path = path[i+len(wildcard):]
+	}
 }
 
 // Returns the callback registered with the given path (key). The values of
 // wildcards are saved to a map.
 // If no callback can be found, a TSR (trailing slash redirect) recommendation is
 // made if a callback exists with an extra (without the) trailing slash for the
-// given path.
+// given path. It is only computed for applications redirecting trailing slashes.
 func (n *node) getValue(path string, app *Application) (callback Next, ps *Params, tsr bool) {
-walk: // Outer loop for walking the tree
-	for {
-		prefix := n.path
-		if len(path) > len(prefix) {
-			if strings.HasPrefix(path, prefix) {
-				path = path[len(prefix):]
-
-				// If this node does not have a wildcard (param or catchAll)
-				// child, we can just look up the next child node and continue
-				// to walk down the tree
-				if !n.wildChild {
-					idxc := path[0]
-					for i := 0; i < len(n.indices); i++ {
-						if n.indices[i] == idxc {
-							n = n.children[i]
-							continue walk
-						}
-					}
+	var leaf *node
+	if leaf, ps, tsr = n.getLeaf(path, app); leaf != nil {
+		callback = leaf.next
+	}
+	return
+}
 
-					// Nothing found.
-					// We can recommend to redirect to the same URL without a
-					// trailing slash if a leaf exists for that path.
-					tsr = (path == "/" && n.next != nil)
-					return
-				}
+// getLeaf is getValue returning the leaf holding the callback.
+func (n *node) getLeaf(path string, app *Application) (leaf *node, ps *Params, tsr bool) {
+	var pool *paramsPool
+	if app != nil {
+		pool = &app.params
+	}
+	if leaf, ps = n.match(path, pool, nil); leaf != nil {
+		return leaf, ps, false
+	}
 
-				// Callback wildcard child
-				n = n.children[0]
-				switch n.nType {
-				case param:
-					// Find param end (either '/' or path end)
-					end := strings.IndexByte(path, '/')
-					if end < 0 {
-						end = len(path)
-					}
+	pool.put(ps)
+	ps = nil
 
-					// Save param value
-					if app != nil {
-						if ps == nil {
-							ps = app.getParams()
-						}
-						// Expand slice within preallocated capacity
-						i := len(*ps)
-						*ps = (*ps)[:i+1]
-						(*ps)[i] = Param{
-							Key:   n.path[1:],
-							Value: path[:end],
-						}
-					}
+	if app != nil && app.RedirectTrailingSlash && len(path) > 1 {
+		var other *node
+		if path[len(path)-1] == '/' {
+			other, _ = n.match(path[:len(path)-1], nil, nil)
+		} else {
+			other, _ = n.match(path+"/", nil, nil)
+		}
+		tsr = other != nil
+	}
+	return nil, nil, tsr
+}
 
-					// We need to go deeper!
-					if end < len(path) {
-						if len(n.children) > 0 {
-							path = path[end:]
-							n = n.children[0]
-							continue walk
-						}
+// match returns the leaf for the rest of the path below n, collecting the
+// param values into ps, taken from pool. A nil pool only probes for the leaf.
+func (n *node) match(path string, pool *paramsPool, ps *Params) (*node, *Params) {
+walk:
+	// Nodes leaving a single way down are walked without recursion, as there
+	// is nothing to back up to: static-only nodes, and nodes whose only child
+	// is an unconstrained param spanning the whole segment
+	for {
+		if path == "" {
+			if n.next != nil {
+				return n, ps
+			}
+			return nil, ps
+		}
 
-						// ... but we can't
-						tsr = (len(path) == end+1)
-						return
-					}
+		if n.catchAll != nil {
+			break
+		}
 
-					if callback = n.next; callback != nil {
-						return
-					} else if len(n.children) == 1 {
-						// No callback found. Check if a callback for this path + a
-						// trailing slash exists for TSR recommendation
-						n = n.children[0]
-						tsr = (n.path == "/" && n.next != nil) || (n.path == "" && n.indices == "/")
+		switch len(n.params) {
+		case 0:
+			c := path[0]
+			for i := 0; i < len(n.indices); i++ {
+				if c == n.indices[i] {
+					child := n.children[i]
+					if len(path) < len(child.path) || path[:len(child.path)] != child.path {
+						return nil, ps
 					}
+					path = path[len(child.path):]
+					n = child
+					continue walk
+				}
+			}
+			return nil, ps
+		case 1:
+			child := n.params[0]
+			if n.indices != "" || child.constraint != nil || (child.indices != "" && child.indices != "/") {
+				break walk
+			}
+			end := nextSlash(path)
+			if end == 0 {
+				return nil, ps
+			}
+			if pool != nil {
+				if ps == nil {
+					ps = pool.get()
+				}
+				i := len(*ps)
+				*ps = (*ps)[:i+1]
+				(*ps)[i] = Param{
+					Key:   child.key,
+					Value: path[:end],
+				}
+			}
+			path = path[end:]
+			n = child
+			continue walk
+		}
+		break
+	}
 
-					return
+	// Static children first
+	if pos := strings.IndexByte(n.indices, path[0]); pos >= 0 {
+		child := n.children[pos]
+		if strings.HasPrefix(path, child.path) {
+			var leaf *node
+			if leaf, ps = child.match(path[len(child.path):], pool, ps); leaf != nil {
+				return leaf, ps
+			}
+		}
+	}
+
+	// Then params, up to the end of the segment or, for params followed by
+	// static text in the same segment, up to the shortest value that works
+	if len(n.params) > 0 {
+		if end := nextSlash(path); end > 0 {
+			for _, child := range n.params {
+				for e := child.paramEnd(path, 0, end); e > 0; e = child.paramEnd(path, e, end) {
+					value := path[:e]
+
+					// A value rejected by the constraint is not found.
+					// Only real lookups are counted, not Allow probes.
+					if child.constraint != nil && !child.constraint.match(value) {
+						if pool != nil && e == end {
+							child.constraint.rejected.Add(1)
+						}
+						continue
+					}
 
-				case catchAll:
-					// Save param value
-					if app != nil {
+					mark := 0
+					if pool != nil {
 						if ps == nil {
-							ps = app.getParams()
+							ps = pool.get()
 						}
 						// Expand slice within preallocated capacity
-						i := len(*ps)
-						*ps = (*ps)[:i+1]
-						(*ps)[i] = Param{
-							Key:   n.path[2:],
-							Value: path,
+						mark = len(*ps)
+						*ps = (*ps)[:mark+1]
+						(*ps)[mark] = Param{
+							Key:   child.key,
+							Value: value,
 						}
 					}
 
-					callback = n.next
-					return
+					var leaf *node
+					if leaf, ps = child.match(path[e:], pool, ps); leaf != nil {
+						return leaf, ps
+					}
 
-				default:
-					panic("invalid node type")
+					// Dead end, drop the value and try the next candidate
+					if ps != nil {
+						*ps = (*ps)[:mark]
+					}
 				}
 			}
-		} else if path == prefix {
-			// We should have reached the node containing the callback.
-			// Check if this node has a callback registered.
-			if callback = n.next; callback != nil {
-				return
+		}
+	}
+
+	// The catch-all takes whatever is left, including the leading '/'
+	if child := n.catchAll; child != nil && path[0] == '/' && child.next != nil {
+		if pool != nil {
+			if ps == nil {
+				ps = pool.get()
+			}
+			i := len(*ps)
+			*ps = (*ps)[:i+1]
+			(*ps)[i] = Param{
+				Key:   child.key,
+				Value: path,
 			}
+		}
+		return child, ps
+	}
+
+	return nil, ps
+}
 
-			// If there is no callback for this route, but this route has a
-			// wildcard child, there must be a callback for this path with an
-			// additional trailing slash
-			if path == "/" && n.wildChild && n.nType != root {
-				tsr = true
-				return
+// paramEnd returns the next candidate end of the value of param node n in
+// path after from, up to the segment end. Candidates are the positions of
+// the static text following the param in the segment, and the segment end
+// itself. It returns 0 when the candidates are exhausted.
+func (n *node) paramEnd(path string, from, end int) int {
+	if from >= end {
+		return 0
+	}
+	if n.indices != "" && n.indices != "/" {
+		for i := from + 1; i < end; i++ {
+			if strings.IndexByte(n.indices, path[i]) >= 0 {
+				return i
 			}
+		}
+	}
+	return end
+}
+
+// Makes a case-insensitive lookup of the given path and tries to find a handler.
+// It can optionally also fix trailing slashes.
+// It returns the case-corrected path and a bool indicating whether the lookup
+// was successful.
+func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool) {
+	buf := make([]byte, 0, len(path)+1)
+	if ciPath, found := n.findCaseInsensitivePathRec(path, buf); found {
+		return string(ciPath), true
+	}
 
-			if path == "/" && n.nType == static {
-				tsr = true
-				return
+	if fixTrailingSlash && len(path) > 1 {
+		if path[len(path)-1] == '/' {
+			path = path[:len(path)-1]
+		} else {
+			path += "/"
+		}
+		if ciPath, found := n.findCaseInsensitivePathRec(path, buf[:0]); found {
+			return string(ciPath), true
+		}
+	}
+	return "", false
+}
+
+// Recursive case-insensitive lookup function used by n.findCaseInsensitivePath.
+// It follows the order of match: static children, params, then the catch-all.
+func (n *node) findCaseInsensitivePathRec(path string, ciPath []byte) ([]byte, bool) {
+	if path == "" {
+		return ciPath, n.next != nil
+	}
+
+	for _, child := range n.children {
+		if len(path) >= len(child.path) && strings.EqualFold(path[:len(child.path)], child.path) {
+			if out, found := child.findCaseInsensitivePathRec(path[len(child.path):], append(ciPath, child.path...)); found {
+				return out, true
 			}
+		}
+	}
 
-			// No callback found. Check if a callback for this path + a
-			// trailing slash exists for trailing slash recommendation
-			for i := 0; i < len(n.indices); i++ {
-				if n.indices[i] == '/' {
-					n = n.children[i]
-					tsr = (len(n.path) == 1 && n.next != nil) ||
-						(n.nType == catchAll && n.children[0].next != nil)
-					return
+	if end := nextSlash(path); end > 0 {
+		for _, child := range n.params {
+			for e := child.paramEnd(path, 0, end); e > 0; e = child.paramEnd(path, e, end) {
+				if child.constraint != nil && !child.constraint.match(path[:e]) {
+					continue
+				}
+				if out, found := child.findCaseInsensitivePathRec(path[e:], append(ciPath, path[:e]...)); found {
+					return out, true
 				}
 			}
-			return
 		}
+	}
 
-		// Nothing found. We can recommend to redirect to the same URL with an
-		// extra trailing slash if a leaf exists for that path
-		tsr = (path == "/") ||
-			(len(prefix) == len(path)+1 && prefix[len(path)] == '/' &&
-				path == prefix[:len(prefix)-1] && n.next != nil)
-		return
+	if child := n.catchAll; child != nil && path[0] == '/' && child.next != nil {
+		return append(ciPath, path...), true
 	}
+
+	return ciPath, false
 }
@@ -0,0 +1,229 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTreeStaticParamCatchAllCoexist(t *testing.T) {
+	app := New()
+	for _, path := range []string{
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/:id<int>/orders",
+		"/users/new/drafts",
+		"/files/index",
+		"/files/*path",
+		"/files/:name/raw",
+		"/*fallback",
+	} {
+		path := path
+		app.Get(path, func(c *Ctx) (any, error) {
+			c.SetHeader("X-Route", path)
+			return nil, nil
+		})
+	}
+
+	tests := []struct {
+		path   string
+		route  string
+		params Params
+	}{
+		{path: "/users/new", route: "/users/new"},
+		{path: "/users/42", route: "/users/:id", params: Params{{Key: "id", Value: "42"}}},
+		{path: "/users/new/posts", route: "/users/:id/posts", params: Params{{Key: "id", Value: "new"}}},
+		{path: "/users/new/drafts", route: "/users/new/drafts"},
+		{path: "/users/42/orders", route: "/users/:id<int>/orders", params: Params{{Key: "id", Value: "42"}}},
+		{path: "/users/bob/orders", route: "/*fallback", params: Params{{Key: "fallback", Value: "/users/bob/orders"}}},
+		{path: "/files/index", route: "/files/index"},
+		{path: "/files/index/raw", route: "/files/:name/raw", params: Params{{Key: "name", Value: "index"}}},
+		{path: "/files/css/app.css", route: "/files/*path", params: Params{{Key: "path", Value: "/css/app.css"}}},
+		{path: "/files/", route: "/files/*path", params: Params{{Key: "path", Value: "/"}}},
+		{path: "/other", route: "/*fallback", params: Params{{Key: "fallback", Value: "/other"}}},
+	}
+
+	root := app.table.Load().methodRoots[0]
+	for _, tt := range tests {
+		next, ps, tsr := root.getValue(tt.path, app)
+		if next == nil || tsr {
+			t.Fatalf("%s: expected a match, got tsr=%v", tt.path, tsr)
+		}
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := rec.Header().Get("X-Route"); got != tt.route {
+			t.Fatalf("%s: expected route %q, got %s", tt.path, tt.route, got)
+		}
+
+		var got Params
+		if ps != nil {
+			got = *ps
+		}
+		if len(got) != len(tt.params) {
+			t.Fatalf("%s: expected params %v, got %v", tt.path, tt.params, got)
+		}
+		for i := range got {
+			if got[i] != tt.params[i] {
+				t.Fatalf("%s: expected params %v, got %v", tt.path, tt.params, got)
+			}
+		}
+		app.params.put(ps)
+	}
+}
+
+func TestTreeWildcardConflicts(t *testing.T) {
+	for _, paths := range [][]string{
+		{"/users/:id", "/users/:name"},
+		{"/files/*path", "/files/*name"},
+		{"/users/:id", "/users/:id"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%v: expected panic", paths)
+				}
+			}()
+			root := new(node)
+			for _, path := range paths {
+				root.addRoute(path, func(c *Ctx) (any, error) { return nil, nil })
+			}
+		}()
+	}
+}
+
+func TestTreeFindCaseInsensitivePathBacktracks(t *testing.T) {
+	root := new(node)
+	for _, path := range []string{"/users/new/edit", "/users/:id", "/docs/*path"} {
+		root.addRoute(path, func(c *Ctx) (any, error) { return nil, nil })
+	}
+
+	tests := []struct {
+		path  string
+		fixed string
+		found bool
+	}{
+		{path: "/USERS/NEW/EDIT", fixed: "/users/new/edit", found: true},
+		{path: "/USERS/New", fixed: "/users/New", found: true},
+		{path: "/Docs/A/B", fixed: "/docs/A/B", found: true},
+		{path: "/USERS/42/", fixed: "/users/42", found: true},
+		{path: "/missing", found: false},
+	}
+
+	for _, tt := range tests {
+		fixed, found := root.findCaseInsensitivePath(tt.path, true)
+		if found != tt.found || fixed != tt.fixed {
+			t.Fatalf("%s: expected (%q, %v), got (%q, %v)", tt.path, tt.fixed, tt.found, fixed, found)
+		}
+	}
+}
+
+func TestTreeParamsWithinSegmentAndOptional(t *testing.T) {
+	app := New()
+	for _, path := range []string{
+		"/files/:name",
+		"/files/:name.:ext",
+		"/files/:name.json",
+		"/v:major<int>.:minor<int>/status",
+		"/reports/:year<int>/:month<int>?",
+		"/:lang?",
+	} {
+		path := path
+		app.Get(path, func(c *Ctx) (any, error) {
+			c.SetHeader("X-Route", path)
+			return nil, nil
+		})
+	}
+
+	tests := []struct {
+		path   string
+		route  string
+		params Params
+	}{
+		{path: "/files/readme", route: "/files/:name", params: Params{{Key: "name", Value: "readme"}}},
+		{path: "/files/archive.tar.gz", route: "/files/:name.:ext", params: Params{{Key: "name", Value: "archive"}, {Key: "ext", Value: "tar.gz"}}},
+		{path: "/files/data.json", route: "/files/:name.json", params: Params{{Key: "name", Value: "data"}}},
+		{path: "/files/a.b.json", route: "/files/:name.:ext", params: Params{{Key: "name", Value: "a"}, {Key: "ext", Value: "b.json"}}},
+		{path: "/v1.12/status", route: "/v:major<int>.:minor<int>/status", params: Params{{Key: "major", Value: "1"}, {Key: "minor", Value: "12"}}},
+		{path: "/reports/2024", route: "/reports/:year<int>/:month<int>?", params: Params{{Key: "year", Value: "2024"}}},
+		{path: "/reports/2024/05", route: "/reports/:year<int>/:month<int>?", params: Params{{Key: "year", Value: "2024"}, {Key: "month", Value: "05"}}},
+		{path: "/", route: "/:lang?"},
+		{path: "/en", route: "/:lang?", params: Params{{Key: "lang", Value: "en"}}},
+	}
+
+	root := app.table.Load().methodRoots[0]
+	for _, tt := range tests {
+		next, ps, _ := root.getValue(tt.path, app)
+		if next == nil {
+			t.Fatalf("%s: expected a match", tt.path)
+		}
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := rec.Header().Get("X-Route"); got != tt.route {
+			t.Fatalf("%s: expected route %q, got %q", tt.path, tt.route, got)
+		}
+
+		var got Params
+		if ps != nil {
+			got = *ps
+		}
+		if len(got) != len(tt.params) {
+			t.Fatalf("%s: expected params %v, got %v", tt.path, tt.params, got)
+		}
+		for i := range got {
+			if got[i] != tt.params[i] {
+				t.Fatalf("%s: expected params %v, got %v", tt.path, tt.params, got)
+			}
+		}
+		app.params.put(ps)
+	}
+
+	if next, _, _ := root.getValue("/reports/2024/may", app); next != nil {
+		t.Fatalf("expected constrained optional param to reject non-numeric month")
+	}
+
+	routes := app.Routes()
+	count := 0
+	for _, r := range routes {
+		if r.Path == "/reports/:year<int>/:month<int>?" {
+			count++
+			if len(r.Constraints) != 2 || !reflect.DeepEqual(r.Params, []string{"year", "month"}) {
+				t.Fatalf("unexpected optional route info: %+v", r)
+			}
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected the optional route to be listed once, got %d", count)
+	}
+}
+
+func TestExpandOptionalAndCountParams(t *testing.T) {
+	expanded := expandOptional("/a/:b?/:c<int>?")
+	if want := []string{"/a", "/a/:b", "/a/:b/:c<int>"}; !reflect.DeepEqual(expanded, want) {
+		t.Fatalf("expected %v, got %v", want, expanded)
+	}
+
+	if n := countParams("/v:major.:minor/:id<[0-9]{2}(?::x)?>/*rest"); n != 4 {
+		t.Fatalf("expected 4 params, got %d", n)
+	}
+
+	for _, path := range []string{
+		"/a/:b?/c",
+		"/a/:b?/:c",
+		"/a/:b?.json",
+		"/a/x:b?",
+		"/a/:b:c",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", path)
+				}
+			}()
+			New().Get(path, func(c *Ctx) (any, error) { return nil, nil })
+		}()
+	}
+}
@@ -32,8 +32,52 @@ type Chain []Middleware
 // RouteGroup groups routes under a shared path prefix and middleware chain.
 type RouteGroup struct {
 	app        *Application
+	host       string
+	version    string
+	parent     *RouteGroup
 	prefix     string
 	middleware Chain
+	options    []RouteOption
+}
+
+// RouteInfo describes a registered route as reported by Application.Routes.
+// Chain names the middleware wrapping the route, outermost first. Routes
+// registered with Any are listed with the method "*". Version is the API
+// version of routes registered with Application.Version.
+type RouteInfo struct {
+	Method      string
+	Host        string
+	Version     string
+	Path        string
+	Name        string
+	Params      []string
+	Constraints []ParamConstraint
+	Middleware  int
+	Chain       []string
+	Meta        map[string]any
+}
+
+// MatchedRoute describes the route serving a request, as returned by
+// Ctx.Route. Method is the method the route was registered for, so HEAD
+// requests answered by a GET route report GET, and Any routes report "*".
+// Meta holds the values attached with the Meta option and must not be
+// modified.
+type MatchedRoute struct {
+	Method  string
+	Host    string
+	Version string
+	Pattern string
+	Name    string
+	Meta    map[string]any
+}
+
+// ParamConstraint describes a constrained path param of a route, e.g. the
+// "uint" in "/users/:id<uint>". Rejected counts the requests that did not
+// match the route because the value violated the constraint.
+type ParamConstraint struct {
+	Param    string
+	Pattern  string
+	Rejected uint64
 }
 
 // Reader
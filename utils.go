@@ -833,6 +833,10 @@ func writeCode(w http.ResponseWriter, r *http.Request, code int) {
 }
 
 func writeCodeByMedia(w http.ResponseWriter, mt mediaType, code int) {
+	writeCodeByContentType(w, contentTypeForMedia(mt), code)
+}
+
+func writeCodeByContentType(w http.ResponseWriter, contentType string, code int) {
 	set := w.Header().Set
 
 	if code == http.StatusUnauthorized {
@@ -840,12 +844,33 @@ func writeCodeByMedia(w http.ResponseWriter, mt mediaType, code int) {
 	}
 
 	if code != http.StatusNoContent {
-		set("Content-Type", contentTypeForMedia(mt))
+		set("Content-Type", contentType)
+		addVaryAccept(w.Header())
 	}
 
 	w.WriteHeader(code)
 }
 
+// varyAccept is the Vary header of negotiated responses. Like the Allow and
+// Sunset headers, it is shared by every response and must not be modified
+// in place; Header().Set and Header().Add replace or extend it safely.
+var varyAccept = []string{"Accept"}
+
+// addVaryAccept adds Accept to the Vary header, as the body depends on it.
+func addVaryAccept(h http.Header) {
+	vary := h["Vary"]
+	if len(vary) == 0 {
+		h["Vary"] = varyAccept
+		return
+	}
+	for _, v := range vary {
+		if strings.EqualFold(v, "Accept") {
+			return
+		}
+	}
+	h["Vary"] = append(vary, "Accept")
+}
+
 // bearerToken return token
 func bearerToken(auth string) string {
 	const prefix = "Bearer "
@@ -3,6 +3,7 @@ package web
 import (
 	"errors"
 	"math"
+	"net/http"
 	"strconv"
 	"testing"
 )
@@ -141,3 +142,21 @@ func TestTryParseIntegerScalarFastPaths(t *testing.T) {
 		}
 	})
 }
+
+func TestAddVaryAcceptSharedSlice(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	addVaryAccept(h)
+	h.Add("Vary", "Origin")
+	addVaryAccept(h)
+	if got := h.Values("Vary"); len(got) != 2 || got[0] != "Accept" || got[1] != "Origin" {
+		t.Fatalf("expected Vary: Accept, Origin, got %q", got)
+	}
+
+	other := http.Header{}
+	addVaryAccept(other)
+	if got := other.Values("Vary"); len(got) != 1 || got[0] != "Accept" {
+		t.Fatalf("expected Vary: Accept, got %q", got)
+	}
+}
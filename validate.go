@@ -0,0 +1,385 @@
+package web
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	ruleRequired = iota
+	ruleMin
+	ruleMax
+	ruleEmail
+	ruleOneOf
+)
+
+// validateRule is a rule of a validate tag.
+type validateRule struct {
+	kind    int
+	n       float64
+	values  []string
+	message string
+}
+
+// validateField is a struct field checked by Validate.
+type validateField struct {
+	index   []int
+	pointer string // a token below the struct, or the whole pointer if bound
+	bound   bool   // the field is filled by Bind
+	omit    bool   // omitempty: zero values skip the rules
+	rules   []validateRule
+	elem    *validateType // of struct fields and of slices of structs
+}
+
+// validateType holds the fields Validate checks for a struct type.
+type validateType struct {
+	fields []validateField
+	err    error
+}
+
+var (
+	validateTypes sync.Map // reflect.Type -> *validateType
+	validateMu    sync.Mutex
+)
+
+// Validate checks the struct v points to against the validate tags of its
+// fields, e.g.
+//
+//	type User struct {
+//		Name  string `json:"name" validate:"required,max=100"`
+//		Email string `json:"email" validate:"required,email"`
+//		Role  string `json:"role" validate:"omitempty,oneof=admin user"`
+//		Age   int    `json:"age" validate:"min=18"`
+//	}
+//
+// The rules are required, min=n and max=n, which bound numbers, the length
+// of strings in characters and the length of slices and maps, email, and
+// oneof, which lists the allowed strings or numbers separated by spaces.
+// Zero values are checked like any other, so Age above rejects 0, unless
+// the tag has omitempty; nil pointers pass every rule but required.
+// Nested structs, also in slices, are checked too. Failures are reported by
+// a *ValidationError, its pointers naming fields as bound by Bind, e.g.
+// "/query/page", or else as decoded from the body, e.g. "/body/items/0/name".
+// A tag with an unknown rule fails with an error naming the tag.
+//
+// TryParseBody and Bind call Validate themselves if
+// Application.AutoValidate is set.
+func (c *Ctx) Validate(v any) error {
+	return validate(v)
+}
+
+// autoValidate validates v for TryParseBody and Bind.
+func (c *Ctx) autoValidate(v any) error {
+	if c.app == nil || !c.app.AutoValidate {
+		return nil
+	}
+	return validate(v)
+}
+
+func validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	vt := validateTypeOf(rv.Type())
+	if vt.err != nil {
+		return vt.err
+	}
+	if fields := vt.check(rv, "/body", nil); fields != nil {
+		return NewValidationError(fields)
+	}
+	return nil
+}
+
+func (vt *validateType) check(rv reflect.Value, prefix string, fields []FieldError) []FieldError {
+	for i := range vt.fields {
+		f := &vt.fields[i]
+		fv := rv.FieldByIndex(f.index)
+		pointer := f.pointer
+		if !f.bound {
+			pointer = prefix + "/" + pointer
+		}
+
+		failed := false
+		empty := f.omit && !hasValue(fv)
+		for _, r := range f.rules {
+			if empty && r.kind != ruleRequired {
+				continue
+			}
+			if !r.check(fv) {
+				fields = append(fields, FieldError{Pointer: pointer, Message: r.message})
+				failed = true
+				break
+			}
+		}
+		if failed || f.elem == nil {
+			continue
+		}
+
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		switch fv.Kind() {
+		case reflect.Struct:
+			fields = f.elem.check(fv, pointer, fields)
+		case reflect.Slice, reflect.Array:
+			for j := 0; j < fv.Len(); j++ {
+				ev := fv.Index(j)
+				for ev.Kind() == reflect.Pointer && !ev.IsNil() {
+					ev = ev.Elem()
+				}
+				if ev.Kind() == reflect.Struct {
+					fields = f.elem.check(ev, pointer+"/"+strconv.Itoa(j), fields)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// check reports whether v passes the rule.
+func (r *validateRule) check(v reflect.Value) bool {
+	if r.kind == ruleRequired {
+		return hasValue(v)
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+
+	switch r.kind {
+	case ruleMin:
+		return validateSize(v) >= r.n
+	case ruleMax:
+		return validateSize(v) <= r.n
+	case ruleEmail:
+		addr, err := mail.ParseAddress(v.String())
+		return err == nil && addr.Address == v.String()
+	case ruleOneOf:
+		s := validateString(v)
+		for _, value := range r.values {
+			if s == value {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// hasValue reports whether v is set: it is no nil pointer, empty slice or
+// map, or other zero value.
+func hasValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	default:
+		return !v.IsZero()
+	}
+}
+
+// validateSize returns the number min and max compare with.
+func validateSize(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String()))
+	default:
+		return float64(v.Len())
+	}
+}
+
+// validateString formats v, a string or number, for oneof.
+func validateString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatNumber(v.Float())
+	default:
+		return v.String()
+	}
+}
+
+// validateTypeOf returns the cached validate fields of the struct type t.
+// Types are built under validateMu, so recursive types see themselves.
+func validateTypeOf(t reflect.Type) *validateType {
+	if vt, ok := validateTypes.Load(t); ok {
+		return vt.(*validateType)
+	}
+
+	validateMu.Lock()
+	defer validateMu.Unlock()
+
+	building := make(map[reflect.Type]*validateType)
+	vt := buildValidateType(t, building)
+	for bt, v := range building {
+		validateTypes.LoadOrStore(bt, v)
+	}
+	return vt
+}
+
+// buildValidateType returns the validate fields of t, adding the types it
+// builds to building.
+func buildValidateType(t reflect.Type, building map[reflect.Type]*validateType) *validateType {
+	if vt, ok := validateTypes.Load(t); ok {
+		return vt.(*validateType)
+	}
+	if vt, ok := building[t]; ok {
+		return vt
+	}
+
+	vt := &validateType{}
+	building[t] = vt
+	vt.err = vt.addFields(t, nil, building, 0)
+	return vt
+}
+
+func (vt *validateType) addFields(t reflect.Type, index []int, building map[reflect.Type]*validateType, depth int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			name = ""
+		}
+		tag := sf.Tag.Get("validate")
+
+		// Fields of embedded structs are promoted
+		if sf.Anonymous && name == "" && tag == "" && sf.Type.Kind() == reflect.Struct {
+			if depth < 8 {
+				if err := vt.addFields(sf.Type, fieldIndex, building, depth+1); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		f := validateField{index: fieldIndex, pointer: escapePointer(name)}
+		if source, name := bindTag(sf); source >= 0 {
+			f.pointer, f.bound = bindPointer(source, name), true
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if tag != "" && tag != "-" {
+			rules, omit, err := parseValidateTag(tag, ft)
+			if err != nil {
+				return fmt.Errorf("Validate: %v in tag `validate:%q` of field %s.%s", err, tag, t, sf.Name)
+			}
+			f.rules, f.omit = rules, omit
+		}
+
+		et := ft
+		if et.Kind() == reflect.Slice || et.Kind() == reflect.Array {
+			et = et.Elem()
+			for et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+		}
+		if et.Kind() == reflect.Struct && et != timeType {
+			f.elem = buildValidateType(et, building)
+			if f.elem.err != nil {
+				return f.elem.err
+			}
+		}
+
+		if f.rules != nil || f.elem != nil {
+			vt.fields = append(vt.fields, f)
+		}
+	}
+	return nil
+}
+
+// parseValidateTag parses the rules of a validate tag for a field of type t,
+// and whether the tag has omitempty.
+func parseValidateTag(tag string, t reflect.Type) (rules []validateRule, omit bool, err error) {
+	for tag != "" {
+		var rule string
+		rule, tag, _ = strings.Cut(tag, ",")
+		key, arg, _ := strings.Cut(rule, "=")
+
+		r := validateRule{}
+		switch key {
+		case "omitempty":
+			omit = true
+			continue
+		case "required":
+			r.kind, r.message = ruleRequired, "is required"
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid rule %q", rule)
+			}
+			r.kind, r.n = ruleMin, n
+			bound := "at least "
+			if key == "max" {
+				r.kind, bound = ruleMax, "at most "
+			}
+			switch t.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64:
+				r.message = "must be " + bound + arg
+			case reflect.String:
+				r.message = "must be " + bound + arg + " characters long"
+			case reflect.Slice, reflect.Map, reflect.Array:
+				r.message = "must have " + bound + arg + " items"
+			default:
+				return nil, false, fmt.Errorf("rule %q on type %s", rule, t)
+			}
+		case "email":
+			if t.Kind() != reflect.String {
+				return nil, false, fmt.Errorf("rule %q on type %s", rule, t)
+			}
+			r.kind, r.message = ruleEmail, "must be a valid email address"
+		case "oneof":
+			switch t.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64, reflect.String:
+			default:
+				return nil, false, fmt.Errorf("rule %q on type %s", rule, t)
+			}
+			r.kind, r.values = ruleOneOf, strings.Fields(arg)
+			if len(r.values) == 0 {
+				return nil, false, fmt.Errorf("invalid rule %q", rule)
+			}
+			r.message = "must be one of " + strings.Join(r.values, ", ")
+		default:
+			return nil, false, fmt.Errorf("unknown rule %q", rule)
+		}
+		rules = append(rules, r)
+	}
+	return rules, omit, nil
+}
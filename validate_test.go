@@ -0,0 +1,188 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateItem struct {
+	Name string `json:"name" validate:"required"`
+	Qty  int    `json:"qty" validate:"min=1,max=10"`
+}
+
+type validateOrder struct {
+	Page   int            `query:"page" validate:"omitempty,min=1"`
+	Email  string         `json:"email" validate:"required,email"`
+	Status string         `json:"status,omitempty" validate:"omitempty,oneof=open closed"`
+	Note   *string        `json:"note,omitempty" validate:"max=3"`
+	Tags   []string       `json:"tags" validate:"max=2"`
+	Items  []validateItem `json:"items" validate:"required"`
+	Parent *validateOrder `json:"parent,omitempty"`
+	Limit  **int          `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+}
+
+func TestCtxValidate(t *testing.T) {
+	long := "long"
+	limit := 1000
+	pLimit := &limit
+	tests := []struct {
+		name  string
+		order validateOrder
+		want  []FieldError
+	}{
+		{
+			name:  "valid",
+			order: validateOrder{Email: "ann@example.com", Status: "open", Items: []validateItem{{Name: "a", Qty: 1}}},
+		},
+		{
+			name: "invalid",
+			order: validateOrder{
+				Page:   -1,
+				Email:  "ann",
+				Status: "lost",
+				Note:   &long,
+				Tags:   []string{"a", "b", "c"},
+				Items:  []validateItem{{Name: "a", Qty: 1}, {Qty: 11}},
+				Parent: &validateOrder{Email: "bob@example.com"},
+				Limit:  &pLimit,
+			},
+			want: []FieldError{
+				{Pointer: "/query/page", Message: "must be at least 1"},
+				{Pointer: "/body/email", Message: "must be a valid email address"},
+				{Pointer: "/body/status", Message: "must be one of open, closed"},
+				{Pointer: "/body/note", Message: "must be at most 3 characters long"},
+				{Pointer: "/body/tags", Message: "must have at most 2 items"},
+				{Pointer: "/body/items/1/name", Message: "is required"},
+				{Pointer: "/body/items/1/qty", Message: "must be at most 10"},
+				{Pointer: "/body/parent/items", Message: "is required"},
+				{Pointer: "/body/limit", Message: "must be at most 100"},
+			},
+		},
+	}
+
+	c := createCtx(nil, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	defer releaseCtx(c)
+
+	for _, tt := range tests {
+		err := c.Validate(&tt.order)
+		if tt.want == nil {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrUnprocessableEntity) {
+			t.Fatalf("%s: expected ErrUnprocessableEntity, got %v", tt.name, err)
+		}
+		if got := errorFields(err); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	c := createCtx(nil, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	defer releaseCtx(c)
+
+	var user struct {
+		Age   int      `json:"age" validate:"min=18"`
+		Role  string   `json:"role" validate:"oneof=admin user"`
+		Nick  string   `json:"nick" validate:"omitempty,min=3"`
+		Tags  []string `json:"tags" validate:"omitempty,min=1"`
+		Email *string  `json:"email" validate:"email"`
+	}
+	want := []FieldError{
+		{Pointer: "/body/age", Message: "must be at least 18"},
+		{Pointer: "/body/role", Message: "must be one of admin, user"},
+	}
+	if got := errorFields(c.Validate(&user)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestValidateAfterParse(t *testing.T) {
+	app := New()
+	app.AutoValidate = true
+	app.SetErrorHandler(JSONErrorHandler(false))
+	app.Post("/orders", func(c *Ctx) (any, error) {
+		var order validateOrder
+		if err := c.Bind(&order); err != nil {
+			return nil, err
+		}
+		return order.Email, nil
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/orders?page=0", strings.NewReader(`{"email":"ann@example.com","items":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+	var body ErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	want := []FieldError{{Pointer: "/body/items", Message: "is required"}}
+	if body.Code != http.StatusUnprocessableEntity || !reflect.DeepEqual(body.Errors, want) {
+		t.Fatalf("unexpected error body: %+v", body)
+	}
+
+	c := createCtx(app, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"qty":20}`)), nil)
+	defer releaseCtx(c)
+	c.r.Header.Set("Content-Type", "application/json")
+
+	var item validateItem
+	if err := c.TryParseBody(&item); len(errorFields(err)) != 2 {
+		t.Fatalf("expected TryParseBody to validate, got %v", errorFields(err))
+	}
+
+	app.AutoValidate = false
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/orders?page=0", strings.NewReader(`{"email":"ann@example.com","items":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected Bind not to validate by default, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	c := createCtx(nil, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	defer releaseCtx(c)
+
+	tests := []any{
+		&struct {
+			A string `validate:"between=1"`
+		}{},
+		&struct {
+			A bool `validate:"min=1"`
+		}{},
+		&struct {
+			A int `validate:"email"`
+		}{},
+		&struct {
+			A int `validate:"max=x"`
+		}{},
+		&struct {
+			A bool `validate:"oneof=true"`
+		}{},
+	}
+	for _, v := range tests {
+		err := c.Validate(v)
+		if err == nil || !strings.HasPrefix(err.Error(), "Validate: ") {
+			t.Fatalf("%T: expected a tag error, got %v", v, err)
+		}
+		tag := string(reflect.TypeOf(v).Elem().Field(0).Tag)
+		if !strings.Contains(err.Error(), tag) {
+			t.Fatalf("%T: expected the error to name %s, got %v", v, tag, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Version returns a route group for version v of the API. A request names
+// its version with a path prefix, see VersionPrefix, with the Api-Version
+// header or with the version parameter of Accept, e.g.
+// "application/json; version=2", tried in this order, and asks for
+// DefaultVersion if it names none.
+// Routes of the requested version are tried before the application's own
+// routes, which serve every version, so "/v2/health" is routed as "/health"
+// either way.
+func (app *Application) Version(v string, middleware ...Middleware) *RouteGroup {
+	if v == "" {
+		panic("version must not be empty")
+	}
+	if strings.IndexByte(v, '/') >= 0 {
+		panic("version must not contain '/' in version '" + v + "'")
+	}
+
+	app.update(func(t *routeTable) {
+		if t.versions[v] != nil {
+			return
+		}
+		if t.versions == nil {
+			t.versions = make(map[string]*router)
+		}
+		t.versions[v] = &router{version: v}
+	})
+
+	return &RouteGroup{
+		app:        app,
+		version:    v,
+		middleware: append(Chain(nil), middleware...),
+	}
+}
+
+// versionRouter returns the router of the API version r asks for, or nil if
+// that version has no routes, along with the path to route, which lacks the
+// version prefix if the path named the version.
+func (t *routeTable) versionRouter(app *Application, r *http.Request) (*router, string) {
+	path := r.URL.Path
+	if prefix := app.VersionPrefix; prefix != "" && strings.HasPrefix(path, prefix) {
+		v, _, _ := strings.Cut(path[len(prefix):], "/")
+		if rt := t.versions[v]; rt != nil {
+			if path = path[len(prefix)+len(v):]; path == "" {
+				path = "/"
+			}
+			return rt, path
+		}
+	}
+
+	v := r.Header.Get("Api-Version")
+	if v == "" {
+		v = mediaTypeParam(r.Header.Get("Accept"), "version")
+	}
+	if v == "" {
+		v = app.DefaultVersion
+	}
+	return t.versions[v], path
+}
+
+// Sunset returns middleware announcing that the routes it wraps go away at
+// the given time, with the Sunset header of RFC 8594, e.g.
+// app.Version("1", web.Sunset(t)). Like Vary and Allow, the header value
+// is one slice shared by every response and must not be modified in place.
+func Sunset(at time.Time) Middleware {
+	header := []string{at.UTC().Format(http.TimeFormat)} // read-only
+	return func(next Next) Next {
+		return func(c *Ctx) (any, error) {
+			c.w.Header()["Sunset"] = header
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersionRouting(t *testing.T) {
+	app := New()
+	app.DefaultVersion = "1"
+	app.VersionPrefix = "/v"
+	app.Get("/health", func(c *Ctx) (any, error) { return "ok:" + c.Version(), nil })
+
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	v1 := app.Version("1", Sunset(sunset))
+	v1.Get("/users/:id", func(c *Ctx) (any, error) { return "v1/" + c.Param("id"), nil })
+
+	v2 := app.Version("2")
+	v2.Get("/users/:id", func(c *Ctx) (any, error) { return c.Route().Version + "/" + c.Param("id"), nil })
+	v2.Group("/admin").Get("/stats", func(c *Ctx) (any, error) { return "stats", nil })
+
+	tests := []struct {
+		method string
+		path   string
+		header string
+		value  string
+		code   int
+		body   string
+		sunset string
+	}{
+		{method: http.MethodGet, path: "/users/7", code: http.StatusOK, body: `"v1/7"`, sunset: "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{method: http.MethodGet, path: "/users/7", header: "Api-Version", value: "2", code: http.StatusOK, body: `"2/7"`},
+		{method: http.MethodGet, path: "/users/7", header: "Accept", value: "application/json; version=2", code: http.StatusOK, body: `"2/7"`},
+		{method: http.MethodGet, path: "/users/7", header: "Accept", value: "application/json;version=\"1\"", code: http.StatusOK, body: `"v1/7"`, sunset: "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{method: http.MethodGet, path: "/v2/users/7", header: "Api-Version", value: "1", code: http.StatusOK, body: `"2/7"`},
+		{method: http.MethodGet, path: "/v2/admin/stats", code: http.StatusOK, body: `"stats"`},
+		{method: http.MethodGet, path: "/admin/stats", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/v3/users/7", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/users/7", header: "Api-Version", value: "3", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/health", header: "Api-Version", value: "2", code: http.StatusOK, body: `"ok:2"`},
+		{method: http.MethodGet, path: "/v2/health", code: http.StatusOK, body: `"ok:2"`},
+		{method: http.MethodPost, path: "/v2/users/7", code: http.StatusMethodNotAllowed},
+		{method: http.MethodOptions, path: "/v2/users/7", code: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s %s: expected status %d, got %d", tt.method, tt.path, tt.value, tt.code, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body+"\n" {
+			t.Fatalf("%s %s %s: expected body %q, got %q", tt.method, tt.path, tt.value, tt.body, rec.Body.String())
+		}
+		if got := rec.Header().Get("Sunset"); got != tt.sunset {
+			t.Fatalf("%s %s %s: expected Sunset %q, got %q", tt.method, tt.path, tt.value, tt.sunset, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/users/7", nil))
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected the Allow header of the version route, got %q", got)
+	}
+
+	routes := app.Routes()
+	if len(routes) != 4 || routes[0].Version != "" || routes[1].Version != "1" || routes[2].Version != "2" || routes[3].Path != "/users/:id" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+}
+
+func TestVersionRemoveAndFallback(t *testing.T) {
+	app := New()
+	app.DefaultVersion = "2"
+	app.Get("/users", func(c *Ctx) (any, error) { return "shared", nil })
+
+	v2 := app.Version("2")
+	v2.Get("/users", func(c *Ctx) (any, error) { return "v2", nil })
+	v2.NotFound(func(c *Ctx) (any, error) { return nil, ErrForbidden })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := serve("/users"); rec.Body.String() != `"v2"`+"\n" {
+		t.Fatalf("expected the version route, got %q", rec.Body.String())
+	}
+	if rec := serve("/missing"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the version fallback, got %d", rec.Code)
+	}
+
+	if !v2.Remove(http.MethodGet, "/users") {
+		t.Fatal("expected the version route to be removed")
+	}
+	if rec := serve("/users"); rec.Body.String() != `"shared"`+"\n" {
+		t.Fatalf("expected the application route, got %q", rec.Body.String())
+	}
+}
+
+func TestVersionInvalidPanics(t *testing.T) {
+	for _, v := range []string{"", "1/2"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%q: expected panic", v)
+				}
+			}()
+			New().Version(v)
+		}()
+	}
+}